@@ -295,8 +295,18 @@ export const api = {
       body: JSON.stringify({ match_id: matchId, message }),
     }),
 
-  getMessages: (matchId: number) =>
-    apiRequest(`/api/chat/${matchId}`),
+  // The API pages newest first; pass next_cursor as beforeId for older
+  // messages. Each page is returned oldest first, ready to render.
+  getMessages: async (matchId: number, beforeId?: number) => {
+    const query = beforeId ? `?before_id=${beforeId}` : ''
+    const res = await apiRequest<{ messages: any[] | null; next_cursor: number | null }>(
+      `/api/chat/${matchId}${query}`
+    )
+    if (res.data) {
+      res.data.messages = [...(res.data.messages ?? [])].reverse()
+    }
+    return res
+  },
 
   // Image uploads
   uploadImages: (formData: FormData) =>
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/rishyym0927/match_backend/internal/config"
+	"github.com/rishyym0927/match_backend/internal/core"
+	"github.com/rishyym0927/match_backend/internal/eval"
+	"github.com/rishyym0927/match_backend/internal/repo"
+)
+
+// evaluate replays historical match requests against a scorer and prints
+// ranking metrics as JSON, so runs can be diffed across configurations:
+//
+//	go run ./cmd/evaluate -scorer rule -k 10 -window 720h > rule.json
+//	go run ./cmd/evaluate -scorer logistic -scorer-config model.json > logistic.json
+func main() {
+	scorerName := flag.String("scorer", core.DefaultScorerName, "registered scorer to evaluate")
+	scorerConfig := flag.String("scorer-config", "", "scorer config file (rule weights or model)")
+	k := flag.Int("k", 10, "cut-off for precision/recall/NDCG")
+	window := flag.Duration("window", 30*24*time.Hour, "how far back the test period starts")
+	pool := flag.Int("pool", 0, "candidates scored per viewer (0 to score all)")
+	out := flag.String("out", "", "write the report here instead of stdout")
+	flag.Parse()
+
+	if *k <= 0 {
+		log.Fatal("-k must be positive")
+	}
+
+	// Load environment variables from .env file
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	cfg := config.Load()
+	ctx := context.Background()
+
+	scorer, err := core.NewScorer(*scorerName, *scorerConfig)
+	if err != nil {
+		log.Fatal("Scorer init failed:", err)
+	}
+
+	// Connect DB
+	pg, err := repo.NewPostgres(ctx, cfg.PostgresDSN)
+	if err != nil {
+		log.Fatal("DB error:", err)
+	}
+	defer pg.Close()
+
+	history, err := pg.LoadEvalHistory(ctx)
+	if err != nil {
+		log.Fatal("Failed to load history:", err)
+	}
+
+	report, err := eval.Run(ctx, history, scorer, eval.Options{
+		K:        *k,
+		Since:    time.Now().Add(-*window),
+		PoolSize: *pool,
+	})
+	if err != nil {
+		log.Fatal("Evaluation failed:", err)
+	}
+	report.Scorer = *scorerName
+	report.ScorerConfig = *scorerConfig
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Fatal("Failed to encode report:", err)
+	}
+	data = append(data, '\n')
+
+	if *out == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		log.Fatal("Failed to write report:", err)
+	}
+}
@@ -7,11 +7,14 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/rishyym0927/match_backend/internal/api"
+	"github.com/rishyym0927/match_backend/internal/cache"
 	"github.com/rishyym0927/match_backend/internal/config"
 	"github.com/rishyym0927/match_backend/internal/core"
+	"github.com/rishyym0927/match_backend/internal/jobs"
 	"github.com/rishyym0927/match_backend/internal/repo"
 	"github.com/rishyym0927/match_backend/internal/storage"
 )
@@ -38,15 +41,43 @@ func main() {
 	}
 	defer pg.Close()
 
-	matcher := core.NewMatcher(pg)
+	scorer, err := core.NewScorer(cfg.Scorer, cfg.ScorerConfig)
+	if err != nil {
+		log.Fatal("Scorer init failed:", err)
+	}
+	recCache := cache.NewMemory(time.Duration(cfg.RecCacheTTLSeconds) * time.Second)
+	matcher := core.NewMatcher(pg, scorer, recCache)
+
+	// User events fan out through Postgres so every replica sees them
+	var pubsub repo.PubSub = repo.NewPgPubSub(pg)
+	if cfg.PubSub == "memory" {
+		pubsub = repo.NewMemoryPubSub()
+	}
+
+	// Shared by the background job and the on-demand /api/match/daily path
+	dailyPicks := &jobs.DailyPicks{Repo: pg, Matcher: matcher, Count: cfg.DailyPicksCount}
 
 	// Create API server
-	server := api.NewServer(cfg, pg, matcher, cloud)
+	server := api.NewServer(cfg, pg, matcher, dailyPicks, cloud, pubsub)
 
 	srv := &http.Server{
 		Addr:    ":" + cfg.Port,
 		Handler: server.Routes(),
 	}
+	// Shutdown waits for requests to finish, so end long-lived streams first
+	srv.RegisterOnShutdown(server.Close)
+
+	// Background jobs stop when jobsCtx is cancelled on shutdown
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	var scheduler jobs.Scheduler
+	scheduler.Every(jobsCtx, "daily-picks", time.Duration(cfg.DailyPicksInterval)*time.Minute, dailyPicks.Run)
+	expireRequests := &jobs.ExpireRequests{Repo: pg, TTL: time.Duration(cfg.RequestTTLHours) * time.Hour}
+	scheduler.Every(jobsCtx, "expire-requests", time.Duration(cfg.RequestExpiryEvery)*time.Minute, expireRequests.Run)
+	go func() {
+		if err := server.RelayEvents(jobsCtx); err != nil {
+			log.Println("Event relay stopped:", err)
+		}
+	}()
 
 	go func() {
 		log.Printf("🚀 Listening on :%s", cfg.Port)
@@ -55,10 +86,27 @@ func main() {
 		}
 	}()
 
+	// Reload scorer config on SIGHUP so weights can be tuned without a redeploy
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			s, err := core.NewScorer(cfg.Scorer, cfg.ScorerConfig)
+			if err != nil {
+				log.Println("Scorer reload failed, keeping previous:", err)
+				continue
+			}
+			matcher.SetScorer(s)
+			log.Printf("🔁 Reloaded scorer %q", cfg.Scorer)
+		}
+	}()
+
 	// Graceful shutdown
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
 	log.Println("🛑 Shutting down...")
 	_ = srv.Shutdown(context.Background())
+	stopJobs()
+	scheduler.Wait()
 }
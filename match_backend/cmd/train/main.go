@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/rishyym0927/match_backend/internal/config"
+	"github.com/rishyym0927/match_backend/internal/core"
+	"github.com/rishyym0927/match_backend/internal/repo"
+)
+
+// train fits the logistic compatibility model on historical match request
+// outcomes and writes it to a file the "logistic" scorer can load:
+//
+//	go run ./cmd/train -out model.json
+//	SCORER=logistic SCORER_CONFIG=model.json ./server
+func main() {
+	defaults := core.DefaultTrainOptions()
+	out := flag.String("out", "logistic_model.json", "where to write the fitted model")
+	iterations := flag.Int("iterations", defaults.Iterations, "gradient descent iterations")
+	learningRate := flag.Float64("lr", defaults.LearningRate, "gradient descent learning rate")
+	l2 := flag.Float64("l2", defaults.L2, "L2 regularisation strength")
+	flag.Parse()
+
+	// Load environment variables from .env file
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	cfg := config.Load()
+	ctx := context.Background()
+
+	// Connect DB
+	pg, err := repo.NewPostgres(ctx, cfg.PostgresDSN)
+	if err != nil {
+		log.Fatal("DB error:", err)
+	}
+	defer pg.Close()
+
+	pairs, err := pg.FetchLabeledPairs(ctx)
+	if err != nil {
+		log.Fatal("Failed to load match outcomes:", err)
+	}
+	log.Printf("Loaded %d decided match requests", len(pairs))
+
+	model, err := core.TrainLogistic(pairs, core.TrainOptions{
+		Iterations:   *iterations,
+		LearningRate: *learningRate,
+		L2:           *l2,
+	})
+	if err != nil {
+		log.Fatal("Training failed:", err)
+	}
+
+	data, err := json.MarshalIndent(model, "", "  ")
+	if err != nil {
+		log.Fatal("Failed to encode model:", err)
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		log.Fatal("Failed to write model:", err)
+	}
+
+	log.Printf("✅ Wrote model to %s (log loss %.4f)", *out, model.LogLoss)
+}
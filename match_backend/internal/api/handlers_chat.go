@@ -2,10 +2,19 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+
+	"github.com/rishyym0927/match_backend/internal/chat"
+	"github.com/rishyym0927/match_backend/internal/repo"
 )
 
 // chatSend sends a chat message in a match
@@ -21,18 +30,57 @@ func (s *Server) chatSend(w http.ResponseWriter, r *http.Request) {
 		s.errorJSON(w, "match_id and message are required", http.StatusBadRequest)
 		return
 	}
+	if len(req.Message) > maxMessageLength {
+		s.errorJSON(w, fmt.Sprintf("message must be at most %d bytes", maxMessageLength), http.StatusBadRequest)
+		return
+	}
 
 	uid := userIDFromCtx(r)
+	if !messageFits(req.MatchID, uid, req.Message) {
+		s.errorJSON(w, "message is too long", http.StatusBadRequest)
+		return
+	}
+	if !s.requireMatchParticipant(w, r, req.MatchID, uid) {
+		return
+	}
 
 	// Insert message
-	if _, err := s.repo.InsertMessage(r.Context(), req.MatchID, uid, req.Message); err != nil {
+	msg, recipientID, err := s.chats.InsertMessage(r.Context(), req.MatchID, uid, req.Message)
+	switch {
+	case errors.Is(err, repo.ErrMatchNotFound):
+		s.errorJSON(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, repo.ErrMatchEnded):
+		s.errorJSON(w, err.Error(), http.StatusGone)
+		return
+	case err != nil:
 		s.errorJSON(w, "failed to send message", http.StatusInternalServerError)
 		return
 	}
 
+	// Push to the other participant on whichever instance they're connected
+	s.broker.Send(r.Context(), recipientID, chat.Event{Type: chat.TypeMessage, Data: msg})
+
 	s.responseJSON(w, map[string]string{"message": "sent"}, http.StatusOK)
 }
 
+// messageFits reports whether the event announcing body stays within the
+// NOTIFY payload limit once JSON escaping has grown it, so a stored message
+// is never silently kept from recipients on other instances. IDs are taken
+// at their widest and the timestamp at full precision, so the real event
+// is never larger.
+func messageFits(matchID, senderID int64, body string) bool {
+	draft := repo.MessageRow{
+		ID:       math.MaxInt64,
+		MatchID:  matchID,
+		SenderID: senderID,
+		Body:     body,
+		SentAt:   time.Now(),
+	}
+	size, err := chat.EnvelopeSize(math.MaxInt64, chat.Event{Type: chat.TypeMessage, Data: draft})
+	return err == nil && size <= repo.MaxNotifyPayload
+}
+
 // chatGet retrieves chat messages for a specific match
 func (s *Server) chatGet(w http.ResponseWriter, r *http.Request) {
 	matchID, err := strconv.ParseInt(chi.URLParam(r, "match_id"), 10, 64)
@@ -40,13 +88,114 @@ func (s *Server) chatGet(w http.ResponseWriter, r *http.Request) {
 		s.errorJSON(w, "invalid match_id", http.StatusBadRequest)
 		return
 	}
+	if !s.requireMatchParticipant(w, r, matchID, userIDFromCtx(r)) {
+		return
+	}
 
-	// Fetch messages
-	msgs, err := s.repo.GetMessages(r.Context(), matchID, maxMessages)
+	page, err := parseMessagePage(r)
 	if err != nil {
+		s.errorJSON(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Fetch messages, newest first
+	msgs, more, err := s.chats.GetMessages(r.Context(), matchID, page)
+	switch {
+	case errors.Is(err, repo.ErrInvalidMessageCursor):
+		s.errorJSON(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
 		s.errorJSON(w, "failed to fetch messages", http.StatusInternalServerError)
 		return
 	}
 
-	s.responseJSON(w, map[string]any{"messages": msgs}, http.StatusOK)
+	// next_cursor continues in the same direction: pass it back as
+	// after_id when paging forward, otherwise as before_id
+	var nextCursor *int64
+	if more && len(msgs) > 0 {
+		next := msgs[len(msgs)-1].ID
+		if page.AfterID > 0 {
+			next = msgs[0].ID
+		}
+		nextCursor = &next
+	}
+
+	s.responseJSON(w, map[string]any{"messages": msgs, "next_cursor": nextCursor}, http.StatusOK)
+}
+
+// chatRead marks the match's messages as read by the user and tells the
+// other participant
+func (s *Server) chatRead(w http.ResponseWriter, r *http.Request) {
+	matchID, err := strconv.ParseInt(chi.URLParam(r, "match_id"), 10, 64)
+	if err != nil {
+		s.errorJSON(w, "invalid match_id", http.StatusBadRequest)
+		return
+	}
+
+	// The body is optional; without it everything is marked read
+	var req ChatReadPayload
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		s.errorJSON(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.MessageID < 0 {
+		s.errorJSON(w, "invalid message_id", http.StatusBadRequest)
+		return
+	}
+
+	uid := userIDFromCtx(r)
+	lastRead, peerID, err := s.chats.MarkMessagesRead(r.Context(), matchID, uid, req.MessageID)
+	switch {
+	case errors.Is(err, repo.ErrNotMatchParticipant):
+		s.errorJSON(w, err.Error(), http.StatusForbidden)
+		return
+	case err != nil:
+		s.errorJSON(w, "failed to mark messages read", http.StatusInternalServerError)
+		return
+	}
+
+	s.broker.Send(r.Context(), peerID, chat.Event{
+		Type: chat.TypeRead,
+		Data: map[string]int64{"match_id": matchID, "last_read_message_id": lastRead},
+	})
+
+	s.responseJSON(w, map[string]int64{"last_read_message_id": lastRead}, http.StatusOK)
+}
+
+// requireMatchParticipant writes a 403 and returns false unless the user
+// belongs to the match
+func (s *Server) requireMatchParticipant(w http.ResponseWriter, r *http.Request, matchID, userID int64) bool {
+	ok, err := s.chats.IsMatchParticipant(r.Context(), matchID, userID)
+	if err != nil {
+		s.errorJSON(w, "failed to check match access", http.StatusInternalServerError)
+		return false
+	}
+	if !ok {
+		s.errorJSON(w, repo.ErrNotMatchParticipant.Error(), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// chatSocket upgrades to a WebSocket that receives the user's chat events.
+// It authenticates itself since browsers can't send headers on upgrade.
+func (s *Server) chatSocket(w http.ResponseWriter, r *http.Request) {
+	uid, err := s.parseToken(tokenFromRequest(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     s.originAllowed,
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied with an error
+		return
+	}
+
+	s.hub.Serve(conn, uid)
 }
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rishyym0927/match_backend/internal/config"
+	"github.com/rishyym0927/match_backend/internal/repo"
+)
+
+const (
+	testMatch = 10
+	alice     = 1
+	bob       = 2
+	mallory   = 3 // not in the match
+)
+
+// fakeChats is an in-memory chatRepo holding one match between alice and bob
+type fakeChats struct {
+	messages []repo.MessageRow
+}
+
+func (f *fakeChats) peer(matchID, userID int64) (int64, bool) {
+	if matchID != testMatch {
+		return 0, false
+	}
+	switch userID {
+	case alice:
+		return bob, true
+	case bob:
+		return alice, true
+	}
+	return 0, false
+}
+
+func (f *fakeChats) IsMatchParticipant(_ context.Context, matchID, userID int64) (bool, error) {
+	_, ok := f.peer(matchID, userID)
+	return ok, nil
+}
+
+func (f *fakeChats) InsertMessage(_ context.Context, matchID, senderID int64, body string) (repo.MessageRow, int64, error) {
+	peer, ok := f.peer(matchID, senderID)
+	if !ok {
+		return repo.MessageRow{}, 0, repo.ErrMatchNotFound
+	}
+	msg := repo.MessageRow{
+		ID:       int64(len(f.messages) + 1),
+		MatchID:  matchID,
+		SenderID: senderID,
+		Body:     body,
+		SentAt:   time.Now(),
+	}
+	f.messages = append(f.messages, msg)
+	return msg, peer, nil
+}
+
+func (f *fakeChats) GetMessages(_ context.Context, matchID int64, _ repo.MessagePage) ([]repo.MessageRow, bool, error) {
+	var out []repo.MessageRow
+	for i := len(f.messages) - 1; i >= 0; i-- {
+		if f.messages[i].MatchID == matchID {
+			out = append(out, f.messages[i])
+		}
+	}
+	return out, false, nil
+}
+
+func (f *fakeChats) MarkMessagesRead(_ context.Context, matchID, userID, messageID int64) (int64, int64, error) {
+	peer, ok := f.peer(matchID, userID)
+	if !ok {
+		return 0, 0, repo.ErrNotMatchParticipant
+	}
+	return messageID, peer, nil
+}
+
+func newTestServer(t *testing.T) (*Server, *fakeChats) {
+	t.Helper()
+
+	cfg := config.Config{JWTSecret: "test-secret", JWTTTLHours: 1}
+	s := NewServer(cfg, nil, nil, nil, nil, repo.NewMemoryPubSub())
+	chats := &fakeChats{}
+	s.chats = chats
+	t.Cleanup(s.Close)
+	return s, chats
+}
+
+// do sends a request as uid through the full router, auth included
+func do(t *testing.T, s *Server, uid int64, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+s.createToken(uid))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	s.Routes().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestChatHandlersRequireParticipant(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"send", http.MethodPost, "/api/chat/send", `{"match_id":10,"message":"hi"}`},
+		{"get", http.MethodGet, "/api/chat/10", ""},
+		{"read", http.MethodPost, "/api/chat/10/read", `{"message_id":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t)
+
+			if rec := do(t, s, alice, tt.method, tt.path, tt.body); rec.Code != http.StatusOK {
+				t.Errorf("participant got %d, want 200: %s", rec.Code, rec.Body)
+			}
+			if rec := do(t, s, mallory, tt.method, tt.path, tt.body); rec.Code != http.StatusForbidden {
+				t.Errorf("non-participant got %d, want 403: %s", rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestChatSendStoresOnlyForParticipants(t *testing.T) {
+	s, chats := newTestServer(t)
+
+	do(t, s, mallory, http.MethodPost, "/api/chat/send", `{"match_id":10,"message":"let me in"}`)
+	if len(chats.messages) != 0 {
+		t.Fatalf("non-participant's message was stored: %+v", chats.messages)
+	}
+
+	do(t, s, bob, http.MethodPost, "/api/chat/send", `{"match_id":10,"message":"hello"}`)
+	if len(chats.messages) != 1 || chats.messages[0].SenderID != bob {
+		t.Fatalf("stored %+v, want one message from bob", chats.messages)
+	}
+}
+
+func TestChatSendRejectsEventsTooBigToPublish(t *testing.T) {
+	s, chats := newTestServer(t)
+
+	// Within maxMessageLength, but every '<' is escaped to six bytes
+	body := `{"match_id":10,"message":"` + strings.Repeat("<", maxMessageLength) + `"}`
+	rec := do(t, s, alice, http.MethodPost, "/api/chat/send", body)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400: %s", rec.Code, rec.Body)
+	}
+	if len(chats.messages) != 0 {
+		t.Fatal("oversized message was stored")
+	}
+}
@@ -30,6 +30,7 @@ func (s *Server) submitScore(w http.ResponseWriter, r *http.Request) {
 		s.errorJSON(w, "failed to update scores", http.StatusInternalServerError)
 		return
 	}
+	s.matcher.Invalidate(r.Context(), uid)
 
 	s.responseJSON(w, map[string]any{
 		"message":     "score updated",
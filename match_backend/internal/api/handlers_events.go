@@ -0,0 +1,65 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// sseHeartbeat keeps idle streams open through proxies
+const sseHeartbeat = 25 * time.Second
+
+// eventStream streams the user's events (match requests, accepted
+// requests, new matches, messages and read receipts) as Server-Sent Events.
+// It authenticates itself since EventSource can't send headers.
+func (s *Server) eventStream(w http.ResponseWriter, r *http.Request) {
+	uid, err := s.parseToken(tokenFromRequest(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.errorJSON(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	sub := s.hub.Subscribe(uid)
+	if sub == nil {
+		s.errorJSON(w, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case d, ok := <-sub.Events():
+			if !ok {
+				// Dropped as a slow consumer or shutting down; the client reconnects
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", d.Type, d.Payload); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
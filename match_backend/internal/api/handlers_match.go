@@ -1,8 +1,20 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/rishyym0927/match_backend/internal/chat"
+	"github.com/rishyym0927/match_backend/internal/core"
+	"github.com/rishyym0927/match_backend/internal/jobs"
+	"github.com/rishyym0927/match_backend/internal/repo"
 )
 
 // matchRecommendations returns match recommendations based on preferences
@@ -10,11 +22,22 @@ func (s *Server) matchRecommendations(w http.ResponseWriter, r *http.Request) {
 	// Get viewer ID from context
 	viewerID := userIDFromCtx(r)
 
+	// Saved preferences fill in any filters missing from the query
+	saved, err := s.repo.GetUserPreferences(r.Context(), viewerID)
+	if err != nil {
+		s.errorJSON(w, "failed to fetch preferences", http.StatusInternalServerError)
+		return
+	}
+
 	// Parse query parameters
-	prefs := s.parseMatchPreferences(r)
+	prefs := s.parseMatchPreferences(r, saved)
 
 	// Use the matcher service to get scored and sorted recommendations
 	recommendations, err := s.matcher.Recommend(r.Context(), viewerID, prefs)
+	if errors.Is(err, core.ErrInvalidCursor) {
+		s.errorJSON(w, "invalid cursor", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		s.errorJSON(w, "failed to fetch recommendations", http.StatusInternalServerError)
 		return
@@ -23,6 +46,73 @@ func (s *Server) matchRecommendations(w http.ResponseWriter, r *http.Request) {
 	s.responseJSON(w, recommendations, http.StatusOK)
 }
 
+// matchExplain explains how a specific user scores against the viewer
+func (s *Server) matchExplain(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		s.errorJSON(w, "invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	viewerID := userIDFromCtx(r)
+	if id == viewerID {
+		s.errorJSON(w, "cannot explain a match with yourself", http.StatusBadRequest)
+		return
+	}
+	if s.hiddenByBlock(w, r, viewerID, id) {
+		return
+	}
+
+	explanation, err := s.matcher.Explain(r.Context(), viewerID, id)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		s.errorJSON(w, "user not found", http.StatusNotFound)
+		return
+	case err != nil:
+		s.errorJSON(w, "failed to explain match", http.StatusInternalServerError)
+		return
+	}
+
+	s.responseJSON(w, explanation, http.StatusOK)
+}
+
+// matchDaily returns today's curated top picks for the user, generating
+// them on the spot if the background job hasn't reached this user yet
+func (s *Server) matchDaily(w http.ResponseWriter, r *http.Request) {
+	uid := userIDFromCtx(r)
+	day := jobs.Today()
+
+	generated, err := s.repo.HasDailyPicks(r.Context(), uid, day)
+	if err != nil {
+		s.errorJSON(w, "failed to fetch daily picks", http.StatusInternalServerError)
+		return
+	}
+	if !generated {
+		if _, err := s.dailyPicks.Generate(r.Context(), uid, day); err != nil {
+			s.errorJSON(w, "failed to generate daily picks", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	picks, err := s.repo.GetDailyPicks(r.Context(), uid, day)
+	if err != nil {
+		s.errorJSON(w, "failed to fetch daily picks", http.StatusInternalServerError)
+		return
+	}
+
+	remaining, err := s.remainingSwipes(r, uid)
+	if err != nil {
+		s.errorJSON(w, "failed to fetch swipe quota", http.StatusInternalServerError)
+		return
+	}
+
+	s.responseJSON(w, map[string]any{
+		"date":             day.Format("2006-01-02"),
+		"picks":            picks,
+		"remaining_swipes": remaining,
+	}, http.StatusOK)
+}
+
 // matchRequest sends a match request to another user
 func (s *Server) matchRequest(w http.ResponseWriter, r *http.Request) {
 	var req MatchRequestPayload
@@ -44,19 +134,50 @@ func (s *Server) matchRequest(w http.ResponseWriter, r *http.Request) {
 		s.errorJSON(w, "cannot send request to yourself", http.StatusBadRequest)
 		return
 	}
+	if s.hiddenByBlock(w, r, sender, req.ReceiverID) {
+		return
+	}
 
-	// Send match request
-	if err := s.repo.SendMatchRequest(r.Context(), sender, req.ReceiverID); err != nil {
+	// Send match request; this also records the swipe against the daily
+	// quota and excludes the receiver from future recommendations
+	result, err := s.repo.SendMatchRequest(r.Context(), sender, req.ReceiverID, s.swipeQuota())
+	switch {
+	case errors.Is(err, repo.ErrSwipeQuotaExceeded):
+		s.errorJSON(w, err.Error(), http.StatusTooManyRequests)
+		return
+	case errors.Is(err, repo.ErrInvalidTransition):
+		s.errorJSON(w, "match request was already answered", http.StatusConflict)
+		return
+	case errors.Is(err, repo.ErrUnmatched), errors.Is(err, repo.ErrMatchEnded):
+		s.errorJSON(w, "you can't match with this user again", http.StatusConflict)
+		return
+	case err != nil:
 		s.errorJSON(w, "failed to send match request", http.StatusInternalServerError)
 		return
 	}
+	s.matcher.Invalidate(r.Context(), sender)
+
+	switch {
+	case result.Matched:
+		s.publishMatch(r.Context(), result.MatchID, sender, req.ReceiverID)
+	case result.Sent:
+		s.broker.Send(r.Context(), req.ReceiverID, chat.Event{
+			Type: chat.TypeMatchRequest,
+			Data: map[string]int64{"sender_id": sender},
+		})
+	}
 
-	// Add to exclusions so this user won't show up in recommendations again
-	if err := s.repo.AddExclusion(r.Context(), sender, req.ReceiverID, "liked"); err != nil {
-		// Don't fail the request if exclusion fails, just continue
+	resp := map[string]any{
+		"message":          "request sent",
+		"matched":          result.Matched,
+		"remaining_swipes": result.Remaining,
+	}
+	if result.Matched {
+		resp["message"] = "it's a match"
+		resp["match_id"] = result.MatchID
 	}
 
-	s.responseJSON(w, map[string]string{"message": "request sent"}, http.StatusOK)
+	s.responseJSON(w, resp, http.StatusOK)
 }
 
 // matchReject handles rejecting a user (swipe left)
@@ -75,13 +196,108 @@ func (s *Server) matchReject(w http.ResponseWriter, r *http.Request) {
 
 	sender := userIDFromCtx(r)
 
-	// Add to exclusions so this user won't show up in recommendations again
-	if err := s.repo.AddExclusion(r.Context(), sender, req.ReceiverID, "rejected"); err != nil {
+	// Add to exclusions so this user won't show up in recommendations
+	// again, counting the swipe against the daily quota
+	remaining, err := s.repo.RejectUser(r.Context(), sender, req.ReceiverID, s.swipeQuota())
+	switch {
+	case errors.Is(err, repo.ErrSwipeQuotaExceeded):
+		s.errorJSON(w, err.Error(), http.StatusTooManyRequests)
+		return
+	case err != nil:
 		s.errorJSON(w, "failed to reject user", http.StatusInternalServerError)
 		return
 	}
+	s.matcher.Invalidate(r.Context(), sender)
 
-	s.responseJSON(w, map[string]string{"message": "rejected"}, http.StatusOK)
+	s.responseJSON(w, map[string]any{
+		"message":          "rejected",
+		"remaining_swipes": remaining,
+	}, http.StatusOK)
+}
+
+// matchUndo reverses the user's most recent swipe (rewind)
+func (s *Server) matchUndo(w http.ResponseWriter, r *http.Request) {
+	uid := userIDFromCtx(r)
+	window := time.Duration(s.cfg.UndoWindowMinutes) * time.Minute
+
+	swipe, err := s.repo.UndoLastSwipe(r.Context(), uid, window)
+	switch {
+	case errors.Is(err, repo.ErrNothingToUndo):
+		s.errorJSON(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, repo.ErrUndoNotAllowed):
+		s.errorJSON(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		s.errorJSON(w, "failed to undo swipe", http.StatusInternalServerError)
+		return
+	}
+	s.matcher.Invalidate(r.Context(), uid)
+
+	s.responseJSON(w, map[string]any{
+		"message":   "undone",
+		"action":    swipe.Action,
+		"target_id": swipe.TargetID,
+	}, http.StatusOK)
+}
+
+// matchWithdraw cancels a pending match request the user has sent
+func (s *Server) matchWithdraw(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		s.errorJSON(w, "invalid request ID", http.StatusBadRequest)
+		return
+	}
+
+	uid := userIDFromCtx(r)
+	receiverID, err := s.repo.WithdrawMatchRequest(r.Context(), uid, id)
+	switch {
+	case errors.Is(err, repo.ErrRequestNotFound):
+		s.errorJSON(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, repo.ErrInvalidTransition):
+		s.errorJSON(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		s.errorJSON(w, "failed to withdraw match request", http.StatusInternalServerError)
+		return
+	}
+	s.matcher.Invalidate(r.Context(), uid)
+
+	s.responseJSON(w, map[string]any{
+		"message":     "withdrawn",
+		"receiver_id": receiverID,
+	}, http.StatusOK)
+}
+
+// matchUnmatch ends a match the user is part of
+func (s *Server) matchUnmatch(w http.ResponseWriter, r *http.Request) {
+	matchID, err := strconv.ParseInt(chi.URLParam(r, "match_id"), 10, 64)
+	if err != nil {
+		s.errorJSON(w, "invalid match_id", http.StatusBadRequest)
+		return
+	}
+
+	uid := userIDFromCtx(r)
+	otherID, err := s.repo.Unmatch(r.Context(), matchID, uid)
+	switch {
+	case errors.Is(err, repo.ErrMatchNotFound):
+		s.errorJSON(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, repo.ErrNotMatchParticipant):
+		s.errorJSON(w, err.Error(), http.StatusForbidden)
+		return
+	case errors.Is(err, repo.ErrMatchEnded):
+		s.errorJSON(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		s.errorJSON(w, "failed to unmatch", http.StatusInternalServerError)
+		return
+	}
+	s.matcher.Invalidate(r.Context(), uid)
+	s.matcher.Invalidate(r.Context(), otherID)
+
+	s.responseJSON(w, map[string]string{"message": "unmatched"}, http.StatusOK)
 }
 
 // matchRespond handles accepting or rejecting a match request
@@ -101,8 +317,18 @@ func (s *Server) matchRespond(w http.ResponseWriter, r *http.Request) {
 	receiver := userIDFromCtx(r)
 
 	// Respond to match request
-	err := s.repo.RespondMatchRequest(r.Context(), req.SenderID, receiver, req.Accept)
-	if err != nil {
+	matchID, err := s.repo.RespondMatchRequest(r.Context(), req.SenderID, receiver, req.Accept)
+	switch {
+	case errors.Is(err, repo.ErrRequestNotFound):
+		s.errorJSON(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, repo.ErrInvalidTransition):
+		s.errorJSON(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, repo.ErrUnmatched), errors.Is(err, repo.ErrMatchEnded):
+		s.errorJSON(w, "you can't match with this user again", http.StatusConflict)
+		return
+	case err != nil:
 		s.errorJSON(w, "failed to respond to match request", http.StatusInternalServerError)
 		return
 	}
@@ -110,6 +336,11 @@ func (s *Server) matchRespond(w http.ResponseWriter, r *http.Request) {
 	msg := "rejected"
 	if req.Accept {
 		msg = "accepted"
+		s.broker.Send(r.Context(), req.SenderID, chat.Event{
+			Type: chat.TypeRequestAccepted,
+			Data: map[string]int64{"receiver_id": receiver, "match_id": matchID},
+		})
+		s.publishMatch(r.Context(), matchID, req.SenderID, receiver)
 	}
 
 	s.responseJSON(w, map[string]string{"message": msg}, http.StatusOK)
@@ -128,6 +359,19 @@ func (s *Server) getIncomingRequests(w http.ResponseWriter, r *http.Request) {
 	s.responseJSON(w, map[string]any{"requests": requests}, http.StatusOK)
 }
 
+// getOutgoingRequests retrieves all pending match requests the user has sent
+func (s *Server) getOutgoingRequests(w http.ResponseWriter, r *http.Request) {
+	uid := userIDFromCtx(r)
+
+	requests, err := s.repo.GetOutgoingMatchRequests(r.Context(), uid)
+	if err != nil {
+		s.errorJSON(w, "failed to fetch outgoing requests", http.StatusInternalServerError)
+		return
+	}
+
+	s.responseJSON(w, map[string]any{"requests": requests}, http.StatusOK)
+}
+
 // getRecentMatches retrieves all recent matches for a user
 func (s *Server) getRecentMatches(w http.ResponseWriter, r *http.Request) {
 	uid := userIDFromCtx(r)
@@ -140,3 +384,15 @@ func (s *Server) getRecentMatches(w http.ResponseWriter, r *http.Request) {
 
 	s.responseJSON(w, map[string]any{"matches": matches}, http.StatusOK)
 }
+
+// publishMatch tells both users about a new match
+func (s *Server) publishMatch(ctx context.Context, matchID, userA, userB int64) {
+	s.broker.Send(ctx, userA, chat.Event{
+		Type: chat.TypeMatch,
+		Data: map[string]int64{"match_id": matchID, "user_id": userB},
+	})
+	s.broker.Send(ctx, userB, chat.Event{
+		Type: chat.TypeMatch,
+		Data: map[string]int64{"match_id": matchID, "user_id": userA},
+	})
+}
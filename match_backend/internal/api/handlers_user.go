@@ -1,12 +1,15 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
 	"mime/multipart"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+
+	"github.com/rishyym0927/match_backend/internal/core"
 )
 
 // getProfile retrieves a user profile by ID
@@ -16,6 +19,9 @@ func (s *Server) getProfile(w http.ResponseWriter, r *http.Request) {
 		s.errorJSON(w, "invalid user ID", http.StatusBadRequest)
 		return
 	}
+	if s.hiddenByBlock(w, r, userIDFromCtx(r), id) {
+		return
+	}
 
 	user, err := s.repo.GetUser(r.Context(), id)
 	if err != nil {
@@ -23,9 +29,119 @@ func (s *Server) getProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if id == userIDFromCtx(r) {
+		s.responseJSON(w, SelfProfile{User: user, Lat: user.Lat, Lon: user.Lon}, http.StatusOK)
+		return
+	}
 	s.responseJSON(w, user, http.StatusOK)
 }
 
+// blockUser blocks another user; both stop seeing each other anywhere
+func (s *Server) blockUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		s.errorJSON(w, "invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	uid := userIDFromCtx(r)
+	if id == uid {
+		s.errorJSON(w, "cannot block yourself", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.repo.GetUser(r.Context(), id); err != nil {
+		s.errorJSON(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	if err := s.repo.BlockUser(r.Context(), uid, id); err != nil {
+		s.errorJSON(w, "failed to block user", http.StatusInternalServerError)
+		return
+	}
+	s.matcher.Invalidate(r.Context(), uid)
+	s.matcher.Invalidate(r.Context(), id)
+
+	s.responseJSON(w, map[string]string{"message": "blocked"}, http.StatusOK)
+}
+
+// reportUser files a report against another user for moderation
+func (s *Server) reportUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		s.errorJSON(w, "invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req ReportPayload
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.errorJSON(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := s.validateReport(&req); err != nil {
+		s.errorJSON(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	uid := userIDFromCtx(r)
+	if id == uid {
+		s.errorJSON(w, "cannot report yourself", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.repo.GetUser(r.Context(), id); err != nil {
+		s.errorJSON(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	reportID, err := s.repo.CreateReport(r.Context(), uid, id, req.Category, req.Details)
+	if err != nil {
+		s.errorJSON(w, "failed to submit report", http.StatusInternalServerError)
+		return
+	}
+
+	s.responseJSON(w, map[string]any{"message": "reported", "report_id": reportID}, http.StatusCreated)
+}
+
+// getPreferences returns the authenticated user's saved match preferences
+func (s *Server) getPreferences(w http.ResponseWriter, r *http.Request) {
+	uid := userIDFromCtx(r)
+
+	prefs, err := s.repo.GetUserPreferences(r.Context(), uid)
+	if err != nil {
+		s.errorJSON(w, "failed to fetch preferences", http.StatusInternalServerError)
+		return
+	}
+
+	s.responseJSON(w, map[string]any{"preferences": prefs}, http.StatusOK)
+}
+
+// updatePreferences replaces the authenticated user's match preferences
+func (s *Server) updatePreferences(w http.ResponseWriter, r *http.Request) {
+	uid := userIDFromCtx(r)
+
+	var prefs core.Preferences
+	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+		s.errorJSON(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate preferences
+	if err := s.validatePreferences(&prefs); err != nil {
+		s.errorJSON(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.repo.UpsertUserPreferences(r.Context(), uid, prefs); err != nil {
+		s.errorJSON(w, "failed to update preferences", http.StatusInternalServerError)
+		return
+	}
+	s.matcher.Invalidate(r.Context(), uid)
+
+	s.responseJSON(w, map[string]any{
+		"message":     "preferences updated",
+		"preferences": prefs,
+	}, http.StatusOK)
+}
+
 // uploadUserImages handles multiple image uploads for a user
 func (s *Server) uploadUserImages(w http.ResponseWriter, r *http.Request) {
 	uid := userIDFromCtx(r)
@@ -55,6 +171,7 @@ func (s *Server) uploadUserImages(w http.ResponseWriter, r *http.Request) {
 		s.errorJSON(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	s.matcher.Invalidate(r.Context(), uid)
 
 	s.responseJSON(w, map[string]any{
 		"message":  "upload successful",
@@ -82,6 +199,9 @@ func (s *Server) getUserImagesById(w http.ResponseWriter, r *http.Request) {
 		s.errorJSON(w, "invalid user ID", http.StatusBadRequest)
 		return
 	}
+	if s.hiddenByBlock(w, r, userIDFromCtx(r), id) {
+		return
+	}
 
 	imgs, err := s.repo.GetUserImages(r.Context(), id)
 	if err != nil {
@@ -106,6 +226,7 @@ func (s *Server) setPrimaryImage(w http.ResponseWriter, r *http.Request) {
 		s.errorJSON(w, "failed to set primary image", http.StatusInternalServerError)
 		return
 	}
+	s.matcher.Invalidate(r.Context(), uid)
 
 	s.responseJSON(w, map[string]string{"message": "primary image set"}, http.StatusOK)
 }
@@ -124,6 +245,7 @@ func (s *Server) deleteUserImage(w http.ResponseWriter, r *http.Request) {
 		s.errorJSON(w, "failed to delete image", http.StatusInternalServerError)
 		return
 	}
+	s.matcher.Invalidate(r.Context(), uid)
 
 	s.responseJSON(w, map[string]string{"message": "deleted"}, http.StatusOK)
 }
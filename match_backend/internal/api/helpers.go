@@ -4,12 +4,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 
 	"github.com/rishyym0927/match_backend/internal/core"
+	"github.com/rishyym0927/match_backend/internal/jobs"
+	"github.com/rishyym0927/match_backend/internal/repo"
 )
 
 // ==================== VALIDATION ====================
@@ -45,41 +49,158 @@ func (s *Server) validateScores(sc *ScoreSubmission) error {
 	return nil
 }
 
+// parseMessagePage reads before_id, after_id and limit for chat history
+func parseMessagePage(r *http.Request) (repo.MessagePage, error) {
+	q := r.URL.Query()
+	var page repo.MessagePage
+
+	for _, p := range []struct {
+		name string
+		dst  *int64
+	}{{"before_id", &page.BeforeID}, {"after_id", &page.AfterID}} {
+		if !q.Has(p.name) {
+			continue
+		}
+		id, err := strconv.ParseInt(q.Get(p.name), 10, 64)
+		if err != nil || id <= 0 {
+			return page, fmt.Errorf("invalid %s", p.name)
+		}
+		*p.dst = id
+	}
+	if page.BeforeID > 0 && page.AfterID > 0 {
+		return page, fmt.Errorf("use either before_id or after_id, not both")
+	}
+
+	page.Limit, _ = strconv.Atoi(q.Get("limit"))
+	if page.Limit <= 0 || page.Limit > maxMessages {
+		page.Limit = defaultMessages
+	}
+	return page, nil
+}
+
+// validateReport validates a user report
+func (s *Server) validateReport(req *ReportPayload) error {
+	if !slices.Contains(repo.ReportCategories, req.Category) {
+		return fmt.Errorf("category must be one of %s", strings.Join(repo.ReportCategories, ", "))
+	}
+	if len(req.Details) > maxReportLength {
+		return fmt.Errorf("details must be at most %d characters", maxReportLength)
+	}
+	return nil
+}
+
+// validatePreferences validates saved match preferences
+func (s *Server) validatePreferences(p *core.Preferences) error {
+	if p.TargetGender != "" && p.TargetGender != "M" && p.TargetGender != "F" {
+		return fmt.Errorf("target_gender must be 'M', 'F' or empty")
+	}
+	if p.AgeMin != 0 && (p.AgeMin < minAge || p.AgeMin > maxAge) {
+		return fmt.Errorf("age_min must be between %d and %d", minAge, maxAge)
+	}
+	if p.AgeMax != 0 && (p.AgeMax < minAge || p.AgeMax > maxAge) {
+		return fmt.Errorf("age_max must be between %d and %d", minAge, maxAge)
+	}
+	if p.AgeMin != 0 && p.AgeMax != 0 && p.AgeMin > p.AgeMax {
+		return fmt.Errorf("age_min must not exceed age_max")
+	}
+	if p.MaxDistanceKm < 0 {
+		return fmt.Errorf("max_distance_km must not be negative")
+	}
+	if p.MinScore < minValidScore || p.MinScore > maxValidScore {
+		return fmt.Errorf("min_score must be between %d and %d", minValidScore, maxValidScore)
+	}
+	return nil
+}
+
 // ==================== PARSING ====================
 
-// parseMatchPreferences extracts match preferences from query parameters
-func (s *Server) parseMatchPreferences(r *http.Request) core.MatchPrefs {
-	gender := r.URL.Query().Get("gender")
-	ageMin, _ := strconv.Atoi(r.URL.Query().Get("age_min"))
-	ageMax, _ := strconv.Atoi(r.URL.Query().Get("age_max"))
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	minScoreStr := r.URL.Query().Get("min_score")
+// parseMatchPreferences extracts match preferences from query parameters.
+// Filters missing from the query fall back to the user's saved preferences.
+func (s *Server) parseMatchPreferences(r *http.Request, saved core.Preferences) core.MatchPrefs {
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
 
 	// Apply default/max limits
 	if limit <= 0 || limit > maxLimit {
 		limit = defaultLimit
 	}
 
+	gender := saved.TargetGender
+	if q.Has("gender") {
+		gender = q.Get("gender")
+	}
 	var targetGender rune
 	if gender != "" {
 		targetGender = []rune(gender)[0]
 	}
 
-	// Only apply min_score filter if explicitly specified
-	minScore := 0 // Default to 0 to show all profiles
-	if minScoreStr != "" {
-		if parsed, err := strconv.Atoi(minScoreStr); err == nil && parsed >= 0 && parsed <= 100 {
+	ageMin := saved.AgeMin
+	if q.Has("age_min") {
+		ageMin, _ = strconv.Atoi(q.Get("age_min"))
+	}
+	ageMax := saved.AgeMax
+	if q.Has("age_max") {
+		ageMax, _ = strconv.Atoi(q.Get("age_max"))
+	}
+
+	// Only apply min_score filter if explicitly specified or saved
+	minScore := saved.MinScore
+	if q.Has("min_score") {
+		minScore = 0 // Default to 0 to show all profiles
+		if parsed, err := strconv.Atoi(q.Get("min_score")); err == nil && parsed >= 0 && parsed <= 100 {
 			minScore = parsed
 		}
 	}
 
+	// Only apply distance filter if a positive radius is given or saved
+	maxDistanceKm := saved.MaxDistanceKm
+	if q.Has("max_distance_km") {
+		maxDistanceKm = 0
+		if parsed, err := strconv.ParseFloat(q.Get("max_distance_km"), 64); err == nil && parsed > 0 {
+			maxDistanceKm = parsed
+		}
+	}
+
+	// Diversity re-ranking is opt-in so it can be A/B tested
+	diversity := 0.0
+	if parsed, err := strconv.ParseFloat(q.Get("diversity"), 64); err == nil && parsed > 0 && parsed <= 1 {
+		diversity = parsed
+	}
+
 	return core.MatchPrefs{
-		TargetGender: targetGender,
-		AgeMin:       ageMin,
-		AgeMax:       ageMax,
-		Limit:        limit,
-		MinScore:     minScore,
+		TargetGender:  targetGender,
+		AgeMin:        ageMin,
+		AgeMax:        ageMax,
+		Limit:         limit,
+		MinScore:      minScore,
+		MaxDistanceKm: maxDistanceKm,
+		Cursor:        q.Get("cursor"),
+		Diversity:     diversity,
+	}
+}
+
+// ==================== QUOTA ====================
+
+// remainingSwipes returns how many likes and rejections the user has left
+// today, or repo.UnlimitedSwipes when no quota is configured
+func (s *Server) remainingSwipes(r *http.Request, uid int64) (int, error) {
+	if s.cfg.DailySwipeQuota <= 0 {
+		return repo.UnlimitedSwipes, nil
+	}
+	used, err := s.repo.CountSwipesSince(r.Context(), uid, jobs.Today())
+	if err != nil {
+		return 0, err
+	}
+	if remaining := s.cfg.DailySwipeQuota - used; remaining > 0 {
+		return remaining, nil
 	}
+	return 0, nil
+}
+
+// swipeQuota is today's swipe allowance, enforced by the repo in the
+// same transaction that records the swipe
+func (s *Server) swipeQuota() repo.SwipeQuota {
+	return repo.SwipeQuota{Limit: s.cfg.DailySwipeQuota, Since: jobs.Today()}
 }
 
 // ==================== JWT ====================
@@ -121,3 +242,33 @@ func (s *Server) errorJSON(w http.ResponseWriter, message string, statusCode int
 		fmt.Printf("JSON encode error: %v\n", err)
 	}
 }
+
+// hiddenByBlock writes a 404 and returns true when either user has
+// blocked the other, so a blocked user looks like one that doesn't exist
+func (s *Server) hiddenByBlock(w http.ResponseWriter, r *http.Request, viewerID, targetID int64) bool {
+	blocked, err := s.repo.IsBlocked(r.Context(), viewerID, targetID)
+	if err != nil {
+		s.errorJSON(w, "failed to check block status", http.StatusInternalServerError)
+		return true
+	}
+	if blocked {
+		s.errorJSON(w, "user not found", http.StatusNotFound)
+		return true
+	}
+	return false
+}
+
+// originAllowed reports whether a request's Origin is in the configured
+// allow list. Requests without an Origin come from non-browser clients.
+func (s *Server) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range s.cfg.AllowedOrigins {
+		if strings.TrimSuffix(allowed, "/") == origin {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -21,31 +22,63 @@ func (s *Server) AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return s.jwtSecret, nil
-		})
-
+		uid, err := s.parseToken(strings.TrimPrefix(authHeader, "Bearer "))
 		if err != nil {
-			fmt.Println("JWT parse error:", err)
-			http.Error(w, "invalid token", http.StatusUnauthorized)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			uidFloat, ok := claims["user_id"].(float64)
-			if !ok {
-				http.Error(w, "invalid claims", http.StatusUnauthorized)
-				return
-			}
-			ctx := context.WithValue(r.Context(), userIDKey, int64(uidFloat))
-			next.ServeHTTP(w, r.WithContext(ctx))
-		} else {
-			http.Error(w, "invalid token", http.StatusUnauthorized)
+		ctx := context.WithValue(r.Context(), userIDKey, uid)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// parseToken validates a JWT and returns the user ID in its claims
+func (s *Server) parseToken(tokenStr string) (int64, error) {
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.jwtSecret, nil
+	})
+
+	if err != nil {
+		return 0, errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return 0, errors.New("invalid token")
+	}
+	uidFloat, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, errors.New("invalid claims")
+	}
+	return int64(uidFloat), nil
+}
+
+// tokenFromRequest reads the bearer token from the Authorization header,
+// falling back to the token query parameter for clients such as browser
+// WebSockets that can't set headers
+func tokenFromRequest(r *http.Request) string {
+	if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		return strings.TrimPrefix(authHeader, "Bearer ")
+	}
+	return r.URL.Query().Get("token")
+}
+
+// redactToken blanks the token query parameter in the request line, which
+// the access log prints, so credentials passed for tokenFromRequest are
+// never written to logs. Handlers still read the token from r.URL.
+func redactToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Has("token") {
+			q.Set("token", "REDACTED")
+			r = r.WithContext(r.Context())
+			r.RequestURI = r.URL.EscapedPath() + "?" + q.Encode()
 		}
+		next.ServeHTTP(w, r)
 	})
 }
 
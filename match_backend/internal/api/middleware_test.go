@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedactTokenHidesTokenFromRequestLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		uri    string
+	}{
+		{"chat socket", "/api/chat/ws?token=secret.jwt.value", "/api/chat/ws?token=REDACTED"},
+		{"event stream", "/api/events/stream?token=secret.jwt.value", "/api/events/stream?token=REDACTED"},
+		{"other params kept", "/api/chat/ws?a=1&token=secret.jwt.value", "/api/chat/ws?a=1&token=REDACTED"},
+		{"no token", "/api/health?x=1", "/api/health?x=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			h := redactToken(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) { got = r }))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if got.RequestURI != tt.uri {
+				t.Errorf("RequestURI = %q, want %q", got.RequestURI, tt.uri)
+			}
+			if strings.Contains(tt.target, "token=") && tokenFromRequest(got) != "secret.jwt.value" {
+				t.Errorf("handler can no longer read the token: %q", tokenFromRequest(got))
+			}
+		})
+	}
+}
@@ -26,6 +26,7 @@ func (s *Server) Routes() http.Handler {
 
 // setupMiddleware configures router middleware
 func (s *Server) setupMiddleware(r *chi.Mux) {
+	r.Use(redactToken) // before Logger, which prints the query string
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
@@ -44,6 +45,12 @@ func (s *Server) setupPublicRoutes(r *chi.Mux) {
 	r.Post("/api/auth/signup", s.signup)
 	r.Post("/api/auth/login", s.login)
 	r.Post("/api/auth/check-email", s.checkEmail)
+
+	// These authenticate themselves from the header or ?token=; EventSource
+	// reconnects often, so redactToken keeping the token out of the access
+	// log matters as much for the stream as for the socket
+	r.Get("/api/chat/ws", s.chatSocket)
+	r.Get("/api/events/stream", s.eventStream)
 }
 
 // setupProtectedRoutes configures protected routes
@@ -58,21 +65,32 @@ func (s *Server) setupProtectedRoutes(r *chi.Mux) {
 		pr.Get("/api/user/images/{id}", s.getUserImagesById)
 		pr.Post("/api/user/image/{id}/primary", s.setPrimaryImage)
 		pr.Delete("/api/user/image/{id}/delete", s.deleteUserImage)
+		pr.Get("/api/user/preferences", s.getPreferences)
+		pr.Put("/api/user/preferences", s.updatePreferences)
+		pr.Post("/api/user/{id}/block", s.blockUser)
+		pr.Post("/api/user/{id}/report", s.reportUser)
 
 		// Chatbot routes
 		pr.Post("/api/chatbot/submit-score", s.submitScore)
 
 		// Matching routes
 		pr.Get("/api/match/recommendations", s.matchRecommendations)
+		pr.Get("/api/match/explain/{id}", s.matchExplain)
+		pr.Get("/api/match/daily", s.matchDaily)
 		pr.Get("/api/match/incoming-requests", s.getIncomingRequests)
+		pr.Get("/api/match/outgoing-requests", s.getOutgoingRequests)
 		pr.Get("/api/match/recent", s.getRecentMatches)
 		pr.Post("/api/match/request", s.matchRequest)
 		pr.Post("/api/match/reject", s.matchReject)
+		pr.Post("/api/match/undo", s.matchUndo)
 		pr.Post("/api/match/respond", s.matchRespond)
+		pr.Delete("/api/match/request/{id}", s.matchWithdraw)
+		pr.Delete("/api/match/{match_id}", s.matchUnmatch)
 
 		// Chat routes
 		pr.Post("/api/chat/send", s.chatSend)
 		pr.Get("/api/chat/{match_id}", s.chatGet)
+		pr.Post("/api/chat/{match_id}/read", s.chatRead)
 
 		// Statistics routes
 		pr.Get("/api/stats/activity", s.getUserStats)
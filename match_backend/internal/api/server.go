@@ -1,28 +1,61 @@
 package api
 
 import (
+	"context"
+
+	"github.com/rishyym0927/match_backend/internal/chat"
 	"github.com/rishyym0927/match_backend/internal/config"
 	"github.com/rishyym0927/match_backend/internal/core"
+	"github.com/rishyym0927/match_backend/internal/jobs"
 	"github.com/rishyym0927/match_backend/internal/repo"
 	"github.com/rishyym0927/match_backend/internal/storage"
 )
 
+// chatRepo defines the DB operations the chat handlers need
+type chatRepo interface {
+	IsMatchParticipant(ctx context.Context, matchID, userID int64) (bool, error)
+	InsertMessage(ctx context.Context, matchID, senderID int64, body string) (repo.MessageRow, int64, error)
+	GetMessages(ctx context.Context, matchID int64, page repo.MessagePage) ([]repo.MessageRow, bool, error)
+	MarkMessagesRead(ctx context.Context, matchID, userID, messageID int64) (int64, int64, error)
+}
+
 // Server encapsulates the HTTP server and its dependencies
 type Server struct {
 	cfg        config.Config
 	repo       *repo.Postgres
+	chats      chatRepo
 	matcher    *core.Matcher
+	dailyPicks *jobs.DailyPicks
 	cloudinary *storage.CloudinaryClient
+	hub        *chat.Hub
+	broker     *chat.Broker
 	jwtSecret  []byte
 }
 
 // NewServer creates a new HTTP server instance
-func NewServer(cfg config.Config, r *repo.Postgres, m *core.Matcher, cloud *storage.CloudinaryClient) *Server {
+func NewServer(cfg config.Config, r *repo.Postgres, m *core.Matcher, picks *jobs.DailyPicks, cloud *storage.CloudinaryClient, ps repo.PubSub) *Server {
+	hub := chat.NewHub()
 	return &Server{
 		cfg:        cfg,
 		repo:       r,
+		chats:      r,
 		matcher:    m,
+		dailyPicks: picks,
 		cloudinary: cloud,
+		hub:        hub,
+		broker:     chat.NewBroker(hub, ps),
 		jwtSecret:  []byte(cfg.JWTSecret),
 	}
 }
+
+// RelayEvents delivers events published by any instance to streams held
+// by this one, until ctx is cancelled
+func (s *Server) RelayEvents(ctx context.Context) error {
+	return s.broker.Run(ctx)
+}
+
+// Close ends open event streams and WebSockets, which
+// http.Server.Shutdown doesn't wait for
+func (s *Server) Close() {
+	s.hub.Close()
+}
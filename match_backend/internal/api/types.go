@@ -1,14 +1,21 @@
 package api
 
+import "github.com/rishyym0927/match_backend/internal/core"
+
 const (
-	maxUploadSize   = 20 << 20 // 20 MB
-	maxImagesUpload = 10
-	defaultLimit    = 10
-	maxLimit        = 50
-	minValidScore   = 0
-	maxValidScore   = 100
-	defaultMinScore = 60
-	maxMessages     = 100
+	maxUploadSize    = 20 << 20 // 20 MB
+	maxImagesUpload  = 10
+	defaultLimit     = 10
+	maxLimit         = 50
+	minValidScore    = 0
+	maxValidScore    = 100
+	defaultMinScore  = 60
+	defaultMessages  = 50
+	maxMessages      = 100
+	maxMessageLength = 4000 // bytes, before JSON escaping
+	minAge           = 18
+	maxAge           = 100
+	maxReportLength  = 2000
 )
 
 // ==================== REQUEST TYPES ====================
@@ -53,3 +60,24 @@ type ChatSendPayload struct {
 	MatchID int64  `json:"match_id"`
 	Message string `json:"message"`
 }
+
+// ReportPayload represents a report filed against another user
+type ReportPayload struct {
+	Category string `json:"category"`
+	Details  string `json:"details"`
+}
+
+// ChatReadPayload marks messages read up to MessageID, or all when it's 0
+type ChatReadPayload struct {
+	MessageID int64 `json:"message_id"`
+}
+
+// ==================== RESPONSE TYPES ====================
+
+// SelfProfile is the caller's own profile, the only one that carries
+// coordinates
+type SelfProfile struct {
+	core.User
+	Lat float64 `json:"lat,omitempty"`
+	Lon float64 `json:"lon,omitempty"`
+}
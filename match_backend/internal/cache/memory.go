@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rishyym0927/match_backend/internal/core"
+)
+
+// Memory is an in-process core.RecommendationCache with a fixed TTL.
+// It suits a single server instance; replicas each keep their own copy.
+type Memory struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	entries   map[int64]map[string]entry
+	lastSweep time.Time
+}
+
+type entry struct {
+	rec     core.Recommendation
+	expires time.Time
+}
+
+// NewMemory creates an in-process cache whose entries live for ttl
+func NewMemory(ttl time.Duration) *Memory {
+	return &Memory{
+		ttl:       ttl,
+		entries:   make(map[int64]map[string]entry),
+		lastSweep: time.Now(),
+	}
+}
+
+// Get returns a cached page if it exists and hasn't expired
+func (m *Memory) Get(_ context.Context, viewerID int64, key string) (core.Recommendation, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[viewerID][key]
+	if !ok {
+		return core.Recommendation{}, false
+	}
+	if time.Now().After(e.expires) {
+		delete(m.entries[viewerID], key)
+		return core.Recommendation{}, false
+	}
+	return e.rec, true
+}
+
+// Set stores a page for the viewer
+func (m *Memory) Set(_ context.Context, viewerID int64, key string, rec core.Recommendation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweep(now)
+
+	if m.entries[viewerID] == nil {
+		m.entries[viewerID] = make(map[string]entry)
+	}
+	m.entries[viewerID][key] = entry{rec: rec, expires: now.Add(m.ttl)}
+}
+
+// Invalidate drops every cached page for the viewer
+func (m *Memory) Invalidate(_ context.Context, viewerID int64) {
+	m.mu.Lock()
+	delete(m.entries, viewerID)
+	m.mu.Unlock()
+}
+
+// sweep removes expired entries at most once per TTL so viewers who
+// never come back don't hold memory forever. Callers must hold m.mu.
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < m.ttl {
+		return
+	}
+	m.lastSweep = now
+
+	for viewerID, pages := range m.entries {
+		for key, e := range pages {
+			if now.After(e.expires) {
+				delete(pages, key)
+			}
+		}
+		if len(pages) == 0 {
+			delete(m.entries, viewerID)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/rishyym0927/match_backend/internal/repo"
+)
+
+// Channel is the pub/sub channel chat events travel on
+const Channel = "chat_events"
+
+// envelope addresses an event to one user across server instances
+type envelope struct {
+	UserID int64           `json:"user_id"`
+	Type   string          `json:"type"`
+	Event  json.RawMessage `json:"event"`
+}
+
+// Broker routes events through a PubSub so they reach a user's
+// subscriptions whichever instance holds them
+type Broker struct {
+	hub *Hub
+	ps  repo.PubSub
+}
+
+// NewBroker creates a broker delivering to hub's local subscriptions
+func NewBroker(hub *Hub, ps repo.PubSub) *Broker {
+	return &Broker{hub: hub, ps: ps}
+}
+
+// EnvelopeSize reports how many bytes Send would publish for ev, so
+// callers can refuse events too big for the PubSub before acting on them
+func EnvelopeSize(userID int64, ev Event) (int, error) {
+	_, msg, err := encode(userID, ev)
+	return len(msg), err
+}
+
+// Send publishes an event for userID. If publishing fails the event is
+// still delivered to subscriptions on this instance.
+func (b *Broker) Send(ctx context.Context, userID int64, ev Event) {
+	payload, msg, err := encode(userID, ev)
+	if err != nil {
+		log.Printf("chat: failed to encode %s event: %v", ev.Type, err)
+		return
+	}
+
+	if err := b.ps.Publish(ctx, Channel, msg); err != nil {
+		log.Printf("chat: publish failed, delivering locally only: %v", err)
+		b.hub.publishRaw(userID, Delivery{Type: ev.Type, Payload: payload})
+	}
+}
+
+// encode returns the event as subscribers receive it and the envelope
+// carrying it over the PubSub
+func encode(userID int64, ev Event) (payload, msg []byte, err error) {
+	payload, err = json.Marshal(ev)
+	if err != nil {
+		return nil, nil, err
+	}
+	msg, err = json.Marshal(envelope{UserID: userID, Type: ev.Type, Event: payload})
+	return payload, msg, err
+}
+
+// Run delivers published events to local subscriptions until ctx is cancelled
+func (b *Broker) Run(ctx context.Context) error {
+	return b.ps.Listen(ctx, Channel, func(msg []byte) {
+		var env envelope
+		if err := json.Unmarshal(msg, &env); err != nil {
+			log.Printf("chat: dropping malformed event: %v", err)
+			return
+		}
+		b.hub.publishRaw(env.UserID, Delivery{Type: env.Type, Payload: env.Event})
+	})
+}
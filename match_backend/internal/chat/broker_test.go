@@ -0,0 +1,176 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rishyym0927/match_backend/internal/repo"
+)
+
+// notifyPubSub mimics PgPubSub: payloads go through a string, as they do
+// with pg_notify, and oversized ones are refused
+type notifyPubSub struct {
+	notes chan string
+}
+
+func newNotifyPubSub() *notifyPubSub {
+	return &notifyPubSub{notes: make(chan string, 8)}
+}
+
+func (n *notifyPubSub) Publish(_ context.Context, _ string, payload []byte) error {
+	if len(payload) > repo.MaxNotifyPayload {
+		return repo.ErrPayloadTooLarge
+	}
+	n.notes <- string(payload)
+	return nil
+}
+
+func (n *notifyPubSub) Listen(ctx context.Context, _ string, fn func([]byte)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case note := <-n.notes:
+			fn([]byte(note))
+		}
+	}
+}
+
+func receive(t *testing.T, sub *Subscription) Delivery {
+	t.Helper()
+	select {
+	case d := <-sub.Events():
+		return d
+	case <-time.After(time.Second):
+		t.Fatal("no event delivered")
+		return Delivery{}
+	}
+}
+
+func TestBrokerRoundTrip(t *testing.T) {
+	hub := NewHub()
+	defer hub.Close()
+	ps := newNotifyPubSub()
+	broker := NewBroker(hub, ps)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go broker.Run(ctx)
+
+	sub := hub.Subscribe(7)
+	other := hub.Subscribe(8)
+
+	body := `<b>"quoted" & escaped</b>` + "\n "
+	broker.Send(ctx, 7, Event{Type: TypeMessage, Data: map[string]string{"body": body}})
+
+	d := receive(t, sub)
+	if d.Type != TypeMessage {
+		t.Fatalf("type = %q, want %q", d.Type, TypeMessage)
+	}
+	var ev struct {
+		Type string
+		Data struct{ Body string }
+	}
+	if err := json.Unmarshal(d.Payload, &ev); err != nil {
+		t.Fatalf("payload %s isn't JSON: %v", d.Payload, err)
+	}
+	if ev.Type != TypeMessage || ev.Data.Body != body {
+		t.Fatalf("decoded %+v, want type %q body %q", ev, TypeMessage, body)
+	}
+
+	select {
+	case d := <-other.Events():
+		t.Fatalf("user 8 got %s", d.Payload)
+	default:
+	}
+}
+
+func TestBrokerFiltersByType(t *testing.T) {
+	hub := NewHub()
+	defer hub.Close()
+	broker := NewBroker(hub, newNotifyPubSub())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go broker.Run(ctx)
+
+	sub := hub.Subscribe(7, TypeMessage)
+	broker.Send(ctx, 7, Event{Type: TypeMatch, Data: 1})
+	broker.Send(ctx, 7, Event{Type: TypeMessage, Data: 2})
+
+	if d := receive(t, sub); d.Type != TypeMessage {
+		t.Fatalf("got %q event, want only %q", d.Type, TypeMessage)
+	}
+}
+
+func TestBrokerDeliversLocallyWhenPublishFails(t *testing.T) {
+	hub := NewHub()
+	defer hub.Close()
+	// Not running the broker: only the local fallback can deliver
+	broker := NewBroker(hub, newNotifyPubSub())
+
+	sub := hub.Subscribe(7)
+	big := strings.Repeat("x", repo.MaxNotifyPayload)
+	broker.Send(context.Background(), 7, Event{Type: TypeMessage, Data: big})
+
+	if d := receive(t, sub); !strings.Contains(string(d.Payload), big) {
+		t.Fatal("oversized event wasn't delivered locally")
+	}
+}
+
+func TestEnvelopeSizeMatchesPublished(t *testing.T) {
+	var published []byte
+	ps := publishFunc(func(p []byte) error { published = p; return nil })
+	broker := NewBroker(NewHub(), ps)
+
+	ev := Event{Type: TypeMessage, Data: strings.Repeat("<", 100)}
+	size, err := EnvelopeSize(7, ev)
+	if err != nil {
+		t.Fatalf("EnvelopeSize: %v", err)
+	}
+	broker.Send(context.Background(), 7, ev)
+
+	if size != len(published) {
+		t.Fatalf("EnvelopeSize = %d, published %d bytes", size, len(published))
+	}
+	// Each '<' is escaped to \u003c, so the envelope is far larger than the data
+	if size < 600 {
+		t.Fatalf("EnvelopeSize = %d, expected escaping to be counted", size)
+	}
+}
+
+func TestBrokerDropsMalformedEnvelope(t *testing.T) {
+	hub := NewHub()
+	defer hub.Close()
+	ps := newNotifyPubSub()
+	broker := NewBroker(hub, ps)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go broker.Run(ctx)
+
+	sub := hub.Subscribe(7)
+	ps.notes <- "not json"
+	broker.Send(ctx, 7, Event{Type: TypeRead, Data: nil})
+
+	if d := receive(t, sub); d.Type != TypeRead {
+		t.Fatalf("got %q event after a malformed one, want %q", d.Type, TypeRead)
+	}
+}
+
+// publishFunc is a PubSub that hands every payload to a function
+type publishFunc func([]byte) error
+
+func (f publishFunc) Publish(_ context.Context, _ string, payload []byte) error {
+	return f(payload)
+}
+
+func (f publishFunc) Listen(ctx context.Context, _ string, _ func([]byte)) error {
+	<-ctx.Done()
+	return nil
+}
+
+var _ repo.PubSub = publishFunc(nil)
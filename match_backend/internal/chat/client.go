@@ -0,0 +1,73 @@
+package chat
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// writeWait is how long a single write may take
+	writeWait = 10 * time.Second
+	// pongWait is how long we wait for a pong before giving up on the peer
+	pongWait = 60 * time.Second
+	// pingPeriod must be shorter than pongWait
+	pingPeriod = pongWait * 9 / 10
+	// maxMessageSize caps inbound frames; clients only send control frames
+	maxMessageSize = 512
+)
+
+// client is one WebSocket connection
+type client struct {
+	conn *websocket.Conn
+	sub  *Subscription
+}
+
+// readPump keeps the read deadline fresh on pongs and notices when the
+// peer goes away. Messages are sent over HTTP, so inbound data is ignored.
+func (c *client) readPump() {
+	defer c.sub.Close()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// writePump writes queued events and pings until the subscription is
+// closed, then closes the connection
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case d, ok := <-c.sub.Events():
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, d.Payload); err != nil {
+				c.sub.Close()
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.sub.Close()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Package chat pushes realtime events (chat messages, read receipts,
+// match updates) to users over WebSockets and event streams.
+package chat
+
+import (
+	"encoding/json"
+	"log"
+	"slices"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Event types
+const (
+	TypeMatchRequest    = "match_request"
+	TypeRequestAccepted = "request_accepted"
+	TypeMatch           = "match"
+	TypeMessage         = "message"
+	TypeRead            = "read"
+)
+
+// sendBuffer is how many events may queue before a subscriber counts as slow
+const sendBuffer = 32
+
+// Event is the envelope written to sockets, e.g. {"type":"message","data":{...}}
+type Event struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// Delivery is an encoded event handed to a subscriber
+type Delivery struct {
+	Type    string
+	Payload []byte // the JSON-encoded Event
+}
+
+// Hub tracks the open subscriptions of each user. A user can hold several
+// at once (tabs, devices) and every one of them receives their events.
+type Hub struct {
+	mu     sync.RWMutex
+	subs   map[int64]map[*Subscription]struct{}
+	closed bool
+}
+
+// NewHub creates an empty hub
+func NewHub() *Hub {
+	return &Hub{subs: make(map[int64]map[*Subscription]struct{})}
+}
+
+// Serve subscribes conn to userID's chat events and pumps them to it
+// until the connection closes. It blocks, so call it from the upgrading
+// handler.
+func (h *Hub) Serve(conn *websocket.Conn, userID int64) {
+	sub := h.Subscribe(userID, TypeMessage, TypeRead)
+	if sub == nil {
+		conn.Close()
+		return
+	}
+
+	c := &client{conn: conn, sub: sub}
+	go c.writePump()
+	c.readPump()
+}
+
+// Subscribe opens a subscription to userID's events, limited to the
+// given types if any are passed. It returns nil once the hub is closed.
+func (h *Hub) Subscribe(userID int64, types ...string) *Subscription {
+	sub := &Subscription{
+		hub:    h,
+		userID: userID,
+		types:  types,
+		events: make(chan Delivery, sendBuffer),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil
+	}
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[*Subscription]struct{})
+	}
+	h.subs[userID][sub] = struct{}{}
+	return sub
+}
+
+// Publish sends an event to every subscription the user has open. Slow
+// subscribers whose buffer is full are dropped rather than blocking.
+func (h *Hub) Publish(userID int64, ev Event) {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("chat: failed to encode %s event: %v", ev.Type, err)
+		return
+	}
+	h.publishRaw(userID, Delivery{Type: ev.Type, Payload: payload})
+}
+
+// publishRaw delivers an already encoded event
+func (h *Hub) publishRaw(userID int64, d Delivery) {
+	h.mu.RLock()
+	var slow []*Subscription
+	for sub := range h.subs[userID] {
+		if !sub.wants(d.Type) {
+			continue
+		}
+		select {
+		case sub.events <- d:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, sub := range slow {
+		log.Printf("chat: dropping slow subscriber for user %d", userID)
+		sub.Close()
+	}
+}
+
+// Close ends every subscription and refuses new ones
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.closed = true
+	var all []*Subscription
+	for _, subs := range h.subs {
+		for sub := range subs {
+			all = append(all, sub)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, sub := range all {
+		sub.Close()
+	}
+}
+
+// Subscription is one stream of a user's events
+type Subscription struct {
+	hub    *Hub
+	userID int64
+	types  []string
+	events chan Delivery
+}
+
+// Events yields deliveries until the subscription is closed
+func (s *Subscription) Events() <-chan Delivery {
+	return s.events
+}
+
+// Close removes the subscription from the hub and closes its channel,
+// which stops whatever is draining it. Safe to call more than once.
+func (s *Subscription) Close() {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs := h.subs[s.userID]
+	if _, ok := subs[s]; !ok {
+		return
+	}
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(h.subs, s.userID)
+	}
+	close(s.events)
+}
+
+func (s *Subscription) wants(eventType string) bool {
+	return len(s.types) == 0 || slices.Contains(s.types, eventType)
+}
@@ -0,0 +1,29 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+)
+
+// RecommendationCache stores recommendation pages per viewer.
+// Implementations must be safe for concurrent use; the in-process one
+// lives in the cache package and a shared backend such as Redis can be
+// swapped in by implementing the same methods.
+type RecommendationCache interface {
+	Get(ctx context.Context, viewerID int64, key string) (Recommendation, bool)
+	Set(ctx context.Context, viewerID int64, key string, rec Recommendation)
+	// Invalidate drops every cached page for the viewer
+	Invalidate(ctx context.Context, viewerID int64)
+}
+
+// prefsKey hashes every field that changes the page Recommend returns,
+// plus the version of the scorer that ranked it
+func prefsKey(prefs MatchPrefs, scorerVersion uint64) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d|%d|%d|%d|%d|%g|%s|%d|%g|%d",
+		scorerVersion, prefs.TargetGender, prefs.AgeMin, prefs.AgeMax, prefs.MinScore,
+		prefs.MaxDistanceKm, prefs.Cursor, prefs.Limit, prefs.Diversity, prefs.PoolSize)
+	return strconv.FormatUint(h.Sum64(), 16)
+}
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// maxSeenIDs bounds the cursor size; candidates pulled forward beyond it
+// may be shown again, which is preferable to unbounded tokens
+const maxSeenIDs = 200
+
+// rankCursor marks how far into the global ranking the pages have got.
+// The next page starts right after (Score, ID) in (score DESC, id ASC)
+// order, skipping Seen: candidates below that point that diversity
+// re-ranking already pulled forward onto an earlier page. An ID of 0
+// means no threshold yet, i.e. start from the top.
+type rankCursor struct {
+	Score float64 `json:"s"`
+	ID    int64   `json:"i"`
+	Seen  []int64 `json:"x,omitempty"`
+}
+
+// encodeCursor turns a rank position into an opaque token
+func encodeCursor(c rankCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a token produced by encodeCursor
+func decodeCursor(token string) (rankCursor, error) {
+	var c rankCursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.ID < 0 || len(c.Seen) > maxSeenIDs {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// rankedBefore reports whether a sorts ahead of b in the global ranking
+func rankedBefore(a, b Candidate) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.User.ID < b.User.ID
+}
+
+// after reports whether cand sorts strictly after the cursor position
+func (c rankCursor) after(cand Candidate) bool {
+	if c.ID == 0 {
+		return true
+	}
+	if cand.Score != c.Score {
+		return cand.Score < c.Score
+	}
+	return cand.User.ID > c.ID
+}
+
+// paginate returns the page after cursor (nil for the first page) and
+// the cursor for the page after that, or nil when nothing is left.
+// ranked must be sorted with rankedBefore.
+func paginate(ranked []Candidate, cursor *rankCursor, limit int, diversity float64) ([]Candidate, *rankCursor) {
+	// Skip everything up to and including the previous page's threshold
+	if cursor != nil {
+		i := sort.Search(len(ranked), func(i int) bool { return cursor.after(ranked[i]) })
+		ranked = ranked[i:]
+	}
+
+	seen := map[int64]bool{}
+	if cursor != nil {
+		for _, id := range cursor.Seen {
+			seen[id] = true
+		}
+	}
+
+	remaining := make([]Candidate, 0, len(ranked))
+	for _, c := range ranked {
+		if !seen[c.User.ID] {
+			remaining = append(remaining, c)
+		}
+	}
+
+	if limit <= 0 || limit > len(remaining) {
+		limit = len(remaining)
+	}
+
+	var picks []int
+	if diversity > 0 {
+		picks = selectDiverse(remaining, limit, diversity)
+	} else {
+		picks = make([]int, limit)
+		for i := range picks {
+			picks[i] = i
+		}
+	}
+
+	page := make([]Candidate, len(picks))
+	for i, idx := range picks {
+		page[i] = remaining[idx]
+		seen[remaining[idx].User.ID] = true
+	}
+
+	if len(remaining) == len(page) {
+		return page, nil
+	}
+
+	// Move the threshold past the longest run of shown candidates and
+	// remember the ones shown beyond it
+	next := rankCursor{}
+	if cursor != nil {
+		next.Score, next.ID = cursor.Score, cursor.ID
+	}
+	i := 0
+	for ; i < len(ranked) && seen[ranked[i].User.ID]; i++ {
+		next.Score, next.ID = ranked[i].Score, ranked[i].User.ID
+	}
+	for _, c := range ranked[i:] {
+		if seen[c.User.ID] && len(next.Seen) < maxSeenIDs {
+			next.Seen = append(next.Seen, c.User.ID)
+		}
+	}
+
+	return page, &next
+}
@@ -0,0 +1,188 @@
+package core
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// rankedPool builds n candidates with many tied scores and a mix of
+// cities, ages and traits, sorted the way rank sorts them
+func rankedPool(n int, seed int64) []Candidate {
+	rng := rand.New(rand.NewSource(seed))
+	cities := []string{"Pune", "Delhi", "Mumbai", "Goa"}
+
+	ranked := make([]Candidate, n)
+	for i := range ranked {
+		score := float64(rng.Intn(20)) // few distinct values, so lots of ties
+		ranked[i] = Candidate{
+			User: User{
+				ID:            int64(i + 1),
+				City:          cities[rng.Intn(len(cities))],
+				Age:           20 + rng.Intn(25),
+				Personality:   rng.Intn(101),
+				Communication: rng.Intn(101),
+				Emotional:     rng.Intn(101),
+				Confidence:    rng.Intn(101),
+			},
+			Score:      score,
+			MatchScore: int(score * 5),
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool { return rankedBefore(ranked[i], ranked[j]) })
+	return ranked
+}
+
+// pageThrough follows cursors, round-tripping each through its token as
+// Recommend does, until no cursor is returned. It returns how often each
+// ID was shown.
+func pageThrough(t *testing.T, ranked []Candidate, cursor *rankCursor, limit int, diversity float64) map[int64]int {
+	t.Helper()
+
+	shown := map[int64]int{}
+	for pages := 0; ; pages++ {
+		if pages > len(ranked) {
+			t.Fatal("paging never ended")
+		}
+
+		page, next := paginate(ranked, cursor, limit, diversity)
+		if len(page) == 0 && next != nil {
+			t.Fatal("empty page with a cursor after it")
+		}
+		if next != nil && len(page) != limit {
+			t.Fatalf("page %d has %d candidates, want %d", pages, len(page), limit)
+		}
+		for _, c := range page {
+			shown[c.User.ID]++
+		}
+		if next == nil {
+			return shown
+		}
+
+		decoded, err := decodeCursor(encodeCursor(*next))
+		if err != nil {
+			t.Fatalf("cursor after page %d doesn't decode: %v", pages, err)
+		}
+		cursor = &decoded
+	}
+}
+
+func TestPaginateCoversEveryCandidateOnce(t *testing.T) {
+	const n = 97
+	ranked := rankedPool(n, 1)
+
+	for _, diversity := range []float64{0, 0.3, 0.9} {
+		for _, limit := range []int{1, 7, 10, 50, n, n + 5} {
+			t.Run(fmt.Sprintf("diversity=%g/limit=%d", diversity, limit), func(t *testing.T) {
+				shown := pageThrough(t, ranked, nil, limit, diversity)
+
+				for _, c := range ranked {
+					switch shown[c.User.ID] {
+					case 1:
+					case 0:
+						t.Errorf("candidate %d was never shown", c.User.ID)
+					default:
+						t.Errorf("candidate %d was shown %d times", c.User.ID, shown[c.User.ID])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestPaginateWithoutDiversityKeepsRankOrder(t *testing.T) {
+	ranked := rankedPool(40, 2)
+
+	var order []int64
+	var cursor *rankCursor
+	for {
+		page, next := paginate(ranked, cursor, 6, 0)
+		for _, c := range page {
+			order = append(order, c.User.ID)
+		}
+		if next == nil {
+			break
+		}
+		if len(next.Seen) != 0 {
+			t.Fatalf("cursor remembers %v, want nothing without diversity", next.Seen)
+		}
+		cursor = next
+	}
+
+	for i, c := range ranked {
+		if order[i] != c.User.ID {
+			t.Fatalf("position %d is candidate %d, want %d", i, order[i], c.User.ID)
+		}
+	}
+}
+
+func TestPaginateSeenOverflow(t *testing.T) {
+	// The top two candidates are identical and unlike everyone else, while
+	// everyone else is alike. With heavy diversity the first page takes the
+	// top candidate and then skips the second in favour of the rest, so
+	// more candidates are shown past the threshold than the cursor can
+	// remember.
+	const n = maxSeenIDs + 100
+	const limit = maxSeenIDs + 50
+
+	ranked := make([]Candidate, n)
+	for i := range ranked {
+		u := User{
+			ID:            int64(i + 1),
+			City:          fmt.Sprintf("city-%d", i),
+			Age:           20 + i*ageBucketYears,
+			Personality:   100,
+			Communication: 100,
+			Emotional:     100,
+			Confidence:    100,
+		}
+		if i < 2 {
+			u.City, u.Age = "twin", 30
+			u.Personality, u.Communication, u.Emotional, u.Confidence = 0, 0, 0, 0
+		}
+		ranked[i] = Candidate{User: u, Score: float64(n - i), MatchScore: 100 - i*10/n}
+	}
+
+	page, next := paginate(ranked, nil, limit, 0.9)
+	for _, c := range page {
+		if c.User.ID == 2 {
+			t.Fatal("second candidate made the first page; the setup no longer overflows the cursor")
+		}
+	}
+	if next == nil {
+		t.Fatal("no cursor after the first page")
+	}
+	if len(next.Seen) != maxSeenIDs {
+		t.Fatalf("cursor remembers %d IDs, want it capped at %d", len(next.Seen), maxSeenIDs)
+	}
+	if _, err := decodeCursor(encodeCursor(*next)); err != nil {
+		t.Fatalf("capped cursor doesn't decode: %v", err)
+	}
+
+	// Candidates beyond the cap may come round again, but none is lost
+	shown := pageThrough(t, ranked, next, 20, 0)
+	for _, c := range page {
+		shown[c.User.ID]++
+	}
+	repeats := 0
+	for _, c := range ranked {
+		switch shown[c.User.ID] {
+		case 0:
+			t.Errorf("candidate %d was never shown", c.User.ID)
+		case 1:
+		default:
+			repeats++
+		}
+	}
+	if overflow := len(page) - 1 - maxSeenIDs; repeats > overflow {
+		t.Errorf("%d candidates repeated, want at most the %d the cursor couldn't remember", repeats, overflow)
+	}
+}
+
+func TestDecodeCursorRejectsOversizedSeen(t *testing.T) {
+	c := rankCursor{Score: 1, ID: 1, Seen: make([]int64, maxSeenIDs+1)}
+	if _, err := decodeCursor(encodeCursor(c)); err != ErrInvalidCursor {
+		t.Fatalf("decodeCursor = %v, want ErrInvalidCursor", err)
+	}
+}
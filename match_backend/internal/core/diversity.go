@@ -0,0 +1,79 @@
+package core
+
+import (
+	"math"
+	"strings"
+)
+
+const (
+	// ageBucketYears groups ages when comparing candidates to each other
+	ageBucketYears = 5
+	// diversityWindow limits re-ranking to the top limit*diversityWindow
+	// candidates so a page never reaches far below its own score range
+	diversityWindow = 5
+)
+
+// candidateSimilarity returns 0–1 for how alike two candidates are
+// across city, age bucket and trait profile
+func candidateSimilarity(a, b User) float64 {
+	sim := 0.0
+	if a.City != "" && strings.EqualFold(a.City, b.City) {
+		sim++
+	}
+	if a.Age/ageBucketYears == b.Age/ageBucketYears {
+		sim++
+	}
+	sim += (similarity01(a.Personality, b.Personality) +
+		similarity01(a.Communication, b.Communication) +
+		similarity01(a.Emotional, b.Emotional) +
+		similarity01(a.Confidence, b.Confidence)) / 4
+	return sim / 3
+}
+
+// selectDiverse picks up to limit candidates with maximal marginal
+// relevance: each pick maximises
+//
+//	(1-diversity)*relevance - diversity*similarity to what's already picked
+//
+// diversity is in [0, 1]; 0 keeps score order. ranked must be sorted
+// best first. It returns indexes into ranked in pick order.
+func selectDiverse(ranked []Candidate, limit int, diversity float64) []int {
+	diversity = clamp01(diversity)
+
+	window := len(ranked)
+	if w := limit * diversityWindow; w < window {
+		window = w
+	}
+	if limit > window {
+		limit = window
+	}
+
+	picked := make([]bool, window)
+	maxSim := make([]float64, window) // similarity to the closest pick so far
+	picks := make([]int, 0, limit)
+
+	for len(picks) < limit {
+		best, bestValue := -1, math.Inf(-1)
+		for i := 0; i < window; i++ {
+			if picked[i] {
+				continue
+			}
+			relevance := float64(ranked[i].MatchScore) / 100
+			value := (1-diversity)*relevance - diversity*maxSim[i]
+			if value > bestValue {
+				best, bestValue = i, value
+			}
+		}
+
+		picked[best] = true
+		picks = append(picks, best)
+
+		for i := 0; i < window; i++ {
+			if !picked[i] {
+				maxSim[i] = math.Max(maxSim[i], candidateSimilarity(ranked[best].User, ranked[i].User))
+			}
+		}
+	}
+
+	return picks
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Score component names shared by scorers and explanations
+const (
+	ComponentTotalScore    = "total_score"
+	ComponentPersonality   = "personality"
+	ComponentCommunication = "communication"
+	ComponentEmotional     = "emotional"
+	ComponentConfidence    = "confidence"
+	ComponentDistance      = "distance"
+)
+
+const (
+	// closeSimilarity is how similar two values must be to be called out
+	closeSimilarity = 0.9
+	// highTraitScore is the trait level both users need to "score highly"
+	highTraitScore = 70
+	// nearbyKm is the distance under which two users count as nearby
+	nearbyKm = 25
+)
+
+// ScoreComponent is one part of a score breakdown
+type ScoreComponent struct {
+	Name         string  `json:"name"`
+	Similarity   float64 `json:"similarity"`   // raw 0–1 similarity
+	Weight       float64 `json:"weight"`       // most points this component can add
+	Contribution float64 `json:"contribution"` // points it actually added
+}
+
+// ScoreResult is a scorer's verdict on one candidate
+type ScoreResult struct {
+	Score      float64
+	Components []ScoreComponent
+	Reasons    []string
+}
+
+// newScoreResult sums the components and explains the strongest ones
+func newScoreResult(viewer, cand User, comps []ScoreComponent) ScoreResult {
+	total := 0.0
+	for _, c := range comps {
+		total += c.Contribution
+	}
+	return ScoreResult{
+		Score:      total,
+		Components: comps,
+		Reasons:    explainComponents(viewer, cand, comps),
+	}
+}
+
+// explainComponents turns a breakdown into human-readable sentences,
+// strongest contribution first. Weak components are left out.
+func explainComponents(viewer, cand User, comps []ScoreComponent) []string {
+	sorted := make([]ScoreComponent, len(comps))
+	copy(sorted, comps)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Contribution > sorted[j].Contribution
+	})
+
+	reasons := []string{}
+	for _, c := range sorted {
+		if c.Weight <= 0 {
+			continue
+		}
+		if r := explainComponent(viewer, cand, c); r != "" {
+			reasons = append(reasons, r)
+		}
+	}
+	return reasons
+}
+
+// explainComponent describes a single component, or returns "" when
+// there is nothing worth saying about it
+func explainComponent(viewer, cand User, c ScoreComponent) string {
+	switch c.Name {
+	case ComponentTotalScore:
+		if c.Similarity >= closeSimilarity {
+			return "Your overall compatibility scores are closely aligned"
+		}
+	case ComponentPersonality, ComponentCommunication, ComponentEmotional, ComponentConfidence:
+		if c.Similarity < closeSimilarity {
+			return ""
+		}
+		a, b := traitValue(viewer, c.Name), traitValue(cand, c.Name)
+		if a >= highTraitScore && b >= highTraitScore {
+			return fmt.Sprintf("You both score highly on %s", c.Name)
+		}
+		return fmt.Sprintf("You have a similar level of %s", c.Name)
+	case ComponentDistance:
+		if km, ok := DistanceKm(viewer, cand); ok && km <= nearbyKm {
+			if km < 1 {
+				return "You live less than 1 km apart"
+			}
+			return fmt.Sprintf("You live %.0f km apart", km)
+		}
+		if viewer.City != "" && strings.EqualFold(viewer.City, cand.City) {
+			return fmt.Sprintf("You're both in %s", cand.City)
+		}
+	}
+	return ""
+}
+
+// traitValue returns the named trait score for a user
+func traitValue(u User, name string) int {
+	switch name {
+	case ComponentPersonality:
+		return u.Personality
+	case ComponentCommunication:
+		return u.Communication
+	case ComponentEmotional:
+		return u.Emotional
+	case ComponentConfidence:
+		return u.Confidence
+	}
+	return 0
+}
@@ -0,0 +1,41 @@
+package core
+
+import "math"
+
+const earthRadiusKm = 6371.0
+
+// HasLocation reports whether the user has stored coordinates.
+// Rows without a location default to (0, 0) in the users table.
+func (u User) HasLocation() bool {
+	return u.Lat != 0 || u.Lon != 0
+}
+
+// DistanceKm returns the great-circle (haversine) distance between two users
+// and false when either of them has no location on file
+func DistanceKm(a, b User) (float64, bool) {
+	if !a.HasLocation() || !b.HasLocation() {
+		return 0, false
+	}
+	return haversineKm(a.Lat, a.Lon, b.Lat, b.Lon), true
+}
+
+// haversineKm computes the great-circle distance between two coordinates
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// distanceSimilarity maps a distance to 0–1 with exponential decay:
+// 1 at the same spot, ~0.37 at falloffKm, approaching 0 far beyond it
+func distanceSimilarity(distanceKm, falloffKm float64) float64 {
+	if falloffKm <= 0 {
+		falloffKm = DefaultDistanceFalloffKm
+	}
+	return clamp01(math.Exp(-distanceKm / falloffKm))
+}
@@ -0,0 +1,205 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// LogisticScorerName is the registry name of the learned scorer
+const LogisticScorerName = "logistic"
+
+func init() {
+	RegisterScorer(LogisticScorerName, LoadLogisticScorer)
+}
+
+// FeatureNames lists the model inputs in the order PairFeatures returns them.
+// Each feature is a 0–1 similarity, so they share a scale without normalising.
+var FeatureNames = []string{
+	ComponentTotalScore,
+	ComponentPersonality,
+	ComponentCommunication,
+	ComponentEmotional,
+	ComponentConfidence,
+	ComponentDistance,
+}
+
+// PairFeatures describes how similar two users are, one value per FeatureNames entry
+func PairFeatures(viewer, cand User) []float64 {
+	d := unknownDistanceSimilarity
+	if km, ok := DistanceKm(viewer, cand); ok {
+		d = distanceSimilarity(km, DefaultDistanceFalloffKm)
+	}
+	return []float64{
+		similarity01(viewer.TotalScore, cand.TotalScore),
+		similarity01(viewer.Personality, cand.Personality),
+		similarity01(viewer.Communication, cand.Communication),
+		similarity01(viewer.Emotional, cand.Emotional),
+		similarity01(viewer.Confidence, cand.Confidence),
+		d,
+	}
+}
+
+// LabeledPair is one historical match request and its outcome
+type LabeledPair struct {
+	Sender   User
+	Receiver User
+	Accepted bool
+}
+
+// LogisticModel holds fitted coefficients as written by cmd/train
+type LogisticModel struct {
+	Features     []string  `json:"features"`
+	Intercept    float64   `json:"intercept"`
+	Coefficients []float64 `json:"coefficients"`
+	Samples      int       `json:"samples"`
+	LogLoss      float64   `json:"log_loss"`
+	TrainedAt    time.Time `json:"trained_at"`
+}
+
+// TrainOptions tunes gradient descent in TrainLogistic
+type TrainOptions struct {
+	Iterations   int
+	LearningRate float64
+	L2           float64 // ridge penalty on the coefficients
+}
+
+// DefaultTrainOptions returns settings that converge on typical data
+func DefaultTrainOptions() TrainOptions {
+	return TrainOptions{Iterations: 2000, LearningRate: 0.5, L2: 0.01}
+}
+
+// TrainLogistic fits a logistic regression of request acceptance on the
+// pair features using full-batch gradient descent
+func TrainLogistic(pairs []LabeledPair, opts TrainOptions) (LogisticModel, error) {
+	if len(pairs) == 0 {
+		return LogisticModel{}, errors.New("no labeled pairs to train on")
+	}
+
+	xs := make([][]float64, len(pairs))
+	ys := make([]float64, len(pairs))
+	for i, p := range pairs {
+		xs[i] = PairFeatures(p.Sender, p.Receiver)
+		if p.Accepted {
+			ys[i] = 1
+		}
+	}
+
+	n := float64(len(pairs))
+	w := make([]float64, len(FeatureNames))
+	b := 0.0
+	grad := make([]float64, len(w))
+
+	for iter := 0; iter < opts.Iterations; iter++ {
+		for j := range grad {
+			grad[j] = 0
+		}
+		gradB := 0.0
+
+		for i, x := range xs {
+			residual := sigmoid(b+dot(w, x)) - ys[i]
+			for j, v := range x {
+				grad[j] += residual * v
+			}
+			gradB += residual
+		}
+
+		for j := range w {
+			w[j] -= opts.LearningRate * (grad[j]/n + opts.L2*w[j])
+		}
+		b -= opts.LearningRate * gradB / n
+	}
+
+	// Report the final log loss so runs can be compared
+	loss := 0.0
+	for i, x := range xs {
+		p := math.Min(math.Max(sigmoid(b+dot(w, x)), 1e-12), 1-1e-12)
+		loss -= ys[i]*math.Log(p) + (1-ys[i])*math.Log(1-p)
+	}
+
+	return LogisticModel{
+		Features:     append([]string(nil), FeatureNames...),
+		Intercept:    b,
+		Coefficients: w,
+		Samples:      len(pairs),
+		LogLoss:      loss / n,
+		TrainedAt:    time.Now().UTC(),
+	}, nil
+}
+
+// LogisticScorer scores candidates with a trained LogisticModel.
+// The score is the predicted acceptance probability as a percentage.
+type LogisticScorer struct {
+	model LogisticModel
+}
+
+// NewLogisticScorer checks that the model matches the current features
+func NewLogisticScorer(model LogisticModel) (*LogisticScorer, error) {
+	if len(model.Coefficients) != len(FeatureNames) || len(model.Features) != len(FeatureNames) {
+		return nil, fmt.Errorf("model has %d coefficients, expected %d", len(model.Coefficients), len(FeatureNames))
+	}
+	for i, name := range FeatureNames {
+		if model.Features[i] != name {
+			return nil, fmt.Errorf("model feature %d is %q, expected %q", i, model.Features[i], name)
+		}
+	}
+	return &LogisticScorer{model: model}, nil
+}
+
+// LoadLogisticScorer reads a model file written by cmd/train
+func LoadLogisticScorer(path string) (Scorer, error) {
+	if path == "" {
+		return nil, errors.New("logistic scorer needs a model file (SCORER_CONFIG)")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read logistic model: %w", err)
+	}
+	var model LogisticModel
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("parse logistic model: %w", err)
+	}
+	return NewLogisticScorer(model)
+}
+
+// MaxScore implements Scorer
+func (l *LogisticScorer) MaxScore() float64 {
+	return 100
+}
+
+// Score implements Scorer. Component contributions are in log-odds,
+// so they explain the ranking but don't sum to the score.
+func (l *LogisticScorer) Score(viewer, cand User) ScoreResult {
+	x := PairFeatures(viewer, cand)
+
+	comps := make([]ScoreComponent, len(x))
+	for i, v := range x {
+		comps[i] = ScoreComponent{
+			Name:         FeatureNames[i],
+			Similarity:   v,
+			Weight:       l.model.Coefficients[i],
+			Contribution: v * l.model.Coefficients[i],
+		}
+	}
+
+	return ScoreResult{
+		Score:      sigmoid(l.model.Intercept+dot(l.model.Coefficients, x)) * 100,
+		Components: comps,
+		Reasons:    explainComponents(viewer, cand, comps),
+	}
+}
+
+func sigmoid(z float64) float64 {
+	return 1 / (1 + math.Exp(-z))
+}
+
+func dot(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
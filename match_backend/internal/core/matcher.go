@@ -9,91 +9,218 @@ import (
 // UserRepo defines the DB operations Matcher needs
 type UserRepo interface {
 	GetUser(ctx context.Context, id int64) (User, error)
-	FetchExclusions(ctx context.Context, viewer int64) (map[int64]struct{}, error)
-	FetchCandidates(ctx context.Context, prefs MatchPrefs) ([]User, int64, error)
-	SendMatchRequest(ctx context.Context, senderID, receiverID int64) error
-	RespondMatchRequest(ctx context.Context, senderID, receiverID int64, accept bool) error
-	GetUserImageURLs(ctx context.Context, userID int64) ([]string, error)
+	FetchCandidates(ctx context.Context, prefs MatchPrefs) ([]User, error)
+	GetUserImageURLsBatch(ctx context.Context, userIDs []int64) (map[int64][]string, error)
 }
 
 // Matcher orchestrates recommendation generation
 type Matcher struct {
-	repo UserRepo
+	repo  UserRepo
+	cache RecommendationCache
+
+	mu     sync.RWMutex
+	scorer Scorer
+	// scorerVersion is part of every cache key, so swapping the scorer
+	// orphans pages ranked by the previous one
+	scorerVersion uint64
 }
 
-func NewMatcher(r UserRepo) *Matcher {
-	return &Matcher{repo: r}
+// NewMatcher creates a Matcher that ranks candidates with the given scorer.
+// cache may be nil to disable caching.
+func NewMatcher(r UserRepo, s Scorer, cache RecommendationCache) *Matcher {
+	return &Matcher{repo: r, scorer: s, cache: cache}
 }
 
-// Recommend computes top matches for a user
+// Scorer returns the scorer currently in use
+func (m *Matcher) Scorer() Scorer {
+	s, _ := m.currentScorer()
+	return s
+}
+
+// currentScorer returns the scorer in use and its version
+func (m *Matcher) currentScorer() (Scorer, uint64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.scorer, m.scorerVersion
+}
+
+// SetScorer swaps the active scorer, e.g. after weights were reloaded.
+// Requests already in flight finish with the previous scorer, and cached
+// pages it ranked are no longer served.
+func (m *Matcher) SetScorer(s Scorer) {
+	m.mu.Lock()
+	m.scorer = s
+	m.scorerVersion++
+	m.mu.Unlock()
+}
+
+// Recommend scores the whole candidate pool, ranks it globally and
+// returns the page that follows prefs.Cursor
 func (m *Matcher) Recommend(ctx context.Context, viewerID int64, prefs MatchPrefs) (Recommendation, error) {
+	var cursor *rankCursor
+	if prefs.Cursor != "" {
+		c, err := decodeCursor(prefs.Cursor)
+		if err != nil {
+			return Recommendation{}, err
+		}
+		cursor = &c
+	}
+
+	// Exclusions and hard filters are applied by the query; every user
+	// left is scored unless prefs.PoolSize caps it
+	prefs.ViewerID = viewerID
+
+	scorer, version := m.currentScorer()
+	key := prefsKey(prefs, version)
+	if m.cache != nil {
+		if rec, ok := m.cache.Get(ctx, viewerID, key); ok {
+			return rec, nil
+		}
+	}
+
 	viewer, err := m.repo.GetUser(ctx, viewerID)
 	if err != nil {
 		return Recommendation{}, err
 	}
 
-	exclusions, err := m.repo.FetchExclusions(ctx, viewerID)
+	candidatesRaw, err := m.repo.FetchCandidates(ctx, prefs)
 	if err != nil {
 		return Recommendation{}, err
 	}
 
-	candidatesRaw, nextCursor, err := m.repo.FetchCandidates(ctx, prefs)
-	if err != nil {
-		return Recommendation{}, err
+	ranked := m.rank(scorer, viewer, candidatesRaw, prefs)
+
+	page, nextCursor := paginate(ranked, cursor, prefs.Limit, prefs.Diversity)
+
+	var next string
+	if nextCursor != nil {
+		next = encodeCursor(*nextCursor)
 	}
 
-	// Parallel scoring
-	var wg sync.WaitGroup
-	sem := make(chan struct{}, 8) // limit concurrency
-	var mu sync.Mutex
-	var results []Candidate
+	// Only the returned page needs images, fetched in one round trip
+	ids := make([]int64, len(page))
+	for i := range page {
+		ids[i] = page[i].User.ID
+	}
+	images, err := m.repo.GetUserImageURLsBatch(ctx, ids)
+	if err == nil {
+		for i := range page {
+			page[i].User.Images = images[page[i].User.ID]
+		}
+	}
+
+	rec := Recommendation{Candidates: page, NextCursor: next}
+	if m.cache != nil {
+		m.cache.Set(ctx, viewerID, key, rec)
+	}
+
+	return rec, nil
+}
+
+// Invalidate drops cached recommendations for a viewer. Call it whenever
+// something that feeds their ranking changes: swipes, scores, images or
+// saved preferences.
+func (m *Matcher) Invalidate(ctx context.Context, viewerID int64) {
+	if m.cache != nil {
+		m.cache.Invalidate(ctx, viewerID)
+	}
+}
+
+// scoreWorkers caps parallel scoring; scoring is CPU-bound
+const scoreWorkers = 8
 
+// rank scores candidates in parallel and sorts them best match first
+func (m *Matcher) rank(scorer Scorer, viewer User, candidatesRaw []User, prefs MatchPrefs) []Candidate {
+	maxScore := scorer.MaxScore()
+
+	type eligible struct {
+		user     User
+		distance *float64
+	}
+	pool := make([]eligible, 0, len(candidatesRaw))
 	for _, c := range candidatesRaw {
-		c := c
-		if _, skip := exclusions[c.ID]; skip || c.ID == viewerID {
+		if c.ID == viewer.ID {
 			continue
 		}
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			sem <- struct{}{}
-			s, reasons := RuleScore(viewer, c, 0) // geo=0 for now
-
-			// Fetch user images
-			images, err := m.repo.GetUserImageURLs(ctx, c.ID)
-			if err == nil && len(images) > 0 {
-				c.Images = images
+		var distance *float64
+		if km, ok := DistanceKm(viewer, c); ok {
+			if prefs.MaxDistanceKm > 0 && km > prefs.MaxDistanceKm {
+				continue
 			}
+			distance = &km
+		}
 
-			// Convert score to percentage (0-100)
-			matchScore := int(s * 100 / 75) // Max score is 75, normalize to 100
-			if matchScore > 100 {
-				matchScore = 100
-			}
+		// Reciprocal check: the viewer must also fit what the candidate wants
+		if c.Preferences != nil && !c.Preferences.Accepts(viewer, distance) {
+			continue
+		}
+
+		pool = append(pool, eligible{user: c, distance: distance})
+	}
 
-			<-sem
-			mu.Lock()
-			results = append(results, Candidate{
-				User:       c,
-				Score:      s,
-				Reasons:    reasons,
-				MatchScore: matchScore,
-			})
-			mu.Unlock()
-		}()
+	// A fixed set of workers each scores every workers-th candidate into
+	// its own slot, so no locking is needed
+	results := make([]Candidate, len(pool))
+	workers := min(scoreWorkers, len(pool))
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < len(pool); i += workers {
+				results[i] = newCandidate(scorer, maxScore, viewer, pool[i].user, pool[i].distance)
+			}
+		}(w)
 	}
 	wg.Wait()
 
-	// Sort by score (DESC) - best matches first
+	// Sort by score (DESC) - best matches first, ties broken by ID
 	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
+		return rankedBefore(results[i], results[j])
 	})
 
-	// Limit
-	if len(results) > prefs.Limit && prefs.Limit > 0 {
-		results = results[:prefs.Limit]
+	return results
+}
+
+// Explain scores a single user against the viewer, e.g. to show why
+// they were recommended. Filters and exclusions are not applied.
+func (m *Matcher) Explain(ctx context.Context, viewerID, candidateID int64) (Candidate, error) {
+	viewer, err := m.repo.GetUser(ctx, viewerID)
+	if err != nil {
+		return Candidate{}, err
+	}
+	cand, err := m.repo.GetUser(ctx, candidateID)
+	if err != nil {
+		return Candidate{}, err
+	}
+
+	var distance *float64
+	if km, ok := DistanceKm(viewer, cand); ok {
+		distance = &km
 	}
 
-	return Recommendation{Candidates: results, NextCursor: nextCursor}, nil
+	scorer := m.Scorer()
+	return newCandidate(scorer, scorer.MaxScore(), viewer, cand, distance), nil
+}
+
+// newCandidate scores cand for viewer and wraps the result
+func newCandidate(scorer Scorer, maxScore float64, viewer, cand User, distance *float64) Candidate {
+	res := scorer.Score(viewer, cand)
+	return Candidate{
+		User:       cand,
+		Score:      res.Score,
+		Reasons:    res.Reasons,
+		Breakdown:  res.Components,
+		MatchScore: matchPercent(res.Score, maxScore), // Convert score to percentage (0-100)
+		DistanceKm: distance,
+	}
+}
+
+// matchPercent normalises a raw score against the scorer's maximum
+func matchPercent(score, maxScore float64) int {
+	if maxScore <= 0 {
+		return 0
+	}
+	return int(clamp01(score/maxScore) * 100)
 }
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultScorerName is the scorer used when none is configured
+const DefaultScorerName = "rule"
+
+// ScorerFactory builds a Scorer from an optional config file path
+type ScorerFactory func(configPath string) (Scorer, error)
+
+var (
+	scorersMu sync.RWMutex
+	scorers   = map[string]ScorerFactory{}
+)
+
+func init() {
+	RegisterScorer(DefaultScorerName, LoadRuleScorer)
+}
+
+// RegisterScorer makes a scorer available by name.
+// It panics if the name is empty or already registered.
+func RegisterScorer(name string, factory ScorerFactory) {
+	scorersMu.Lock()
+	defer scorersMu.Unlock()
+
+	if name == "" || factory == nil {
+		panic("core: RegisterScorer needs a name and a factory")
+	}
+	if _, dup := scorers[name]; dup {
+		panic("core: RegisterScorer called twice for " + name)
+	}
+	scorers[name] = factory
+}
+
+// NewScorer builds the registered scorer with the given name
+func NewScorer(name, configPath string) (Scorer, error) {
+	if name == "" {
+		name = DefaultScorerName
+	}
+
+	scorersMu.RLock()
+	factory, ok := scorers[name]
+	scorersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown scorer %q (available: %v)", name, ScorerNames())
+	}
+
+	return factory(configPath)
+}
+
+// ScorerNames lists registered scorers in alphabetical order
+func ScorerNames() []string {
+	scorersMu.RLock()
+	defer scorersMu.RUnlock()
+
+	names := make([]string, 0, len(scorers))
+	for name := range scorers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -1,6 +1,164 @@
 package core
 
-import "math"
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+const (
+	// DefaultDistanceFalloffKm is used when no falloff is configured
+	DefaultDistanceFalloffKm = 50.0
+
+	// unknownDistanceSimilarity is applied when either user has no location,
+	// so missing coordinates neither reward nor sink a candidate
+	unknownDistanceSimilarity = 0.5
+)
+
+// Scorer computes compatibility between a viewer and a candidate
+type Scorer interface {
+	// Score returns the raw score with its per-component breakdown
+	Score(viewer, cand User) ScoreResult
+	// MaxScore is the highest raw score Score can return, used to
+	// normalise scores into a 0–100 match percentage
+	MaxScore() float64
+}
+
+// RuleWeights configures the rule-based scorer.
+// Component weights are in score points; trait weights are relative
+// and get normalised to sum to 1.
+type RuleWeights struct {
+	TotalScore        float64 `json:"total_score"`
+	Traits            float64 `json:"traits"`
+	Distance          float64 `json:"distance"`
+	Personality       float64 `json:"personality"`
+	Communication     float64 `json:"communication"`
+	Emotional         float64 `json:"emotional"`
+	Confidence        float64 `json:"confidence"`
+	DistanceFalloffKm float64 `json:"distance_falloff_km"`
+}
+
+// DefaultRuleWeights returns the weights the matcher shipped with
+func DefaultRuleWeights() RuleWeights {
+	return RuleWeights{
+		TotalScore:        35,
+		Traits:            40,
+		Distance:          25,
+		Personality:       0.3,
+		Communication:     0.3,
+		Emotional:         0.2,
+		Confidence:        0.2,
+		DistanceFalloffKm: DefaultDistanceFalloffKm,
+	}
+}
+
+// validate rejects weights that would produce meaningless scores
+func (w RuleWeights) validate() error {
+	for name, v := range map[string]float64{
+		"total_score":   w.TotalScore,
+		"traits":        w.Traits,
+		"distance":      w.Distance,
+		"personality":   w.Personality,
+		"communication": w.Communication,
+		"emotional":     w.Emotional,
+		"confidence":    w.Confidence,
+	} {
+		if v < 0 {
+			return fmt.Errorf("weight %q must not be negative", name)
+		}
+	}
+	if w.TotalScore+w.Traits+w.Distance == 0 {
+		return fmt.Errorf("at least one component weight must be positive")
+	}
+	if w.Traits > 0 && w.Personality+w.Communication+w.Emotional+w.Confidence == 0 {
+		return fmt.Errorf("trait weights must not all be zero")
+	}
+	return nil
+}
+
+// RuleScorer is the default hand-written Scorer
+type RuleScorer struct {
+	w RuleWeights
+}
+
+// NewRuleScorer creates a rule-based scorer from the given weights
+func NewRuleScorer(w RuleWeights) (*RuleScorer, error) {
+	if err := w.validate(); err != nil {
+		return nil, err
+	}
+	if w.DistanceFalloffKm <= 0 {
+		w.DistanceFalloffKm = DefaultDistanceFalloffKm
+	}
+
+	// Normalise trait mix so Traits stays the component's real maximum
+	if sum := w.Personality + w.Communication + w.Emotional + w.Confidence; sum > 0 {
+		w.Personality /= sum
+		w.Communication /= sum
+		w.Emotional /= sum
+		w.Confidence /= sum
+	}
+
+	return &RuleScorer{w: w}, nil
+}
+
+// LoadRuleScorer builds a RuleScorer from a JSON weights file.
+// Fields missing from the file keep their default values; an empty
+// path returns the defaults.
+func LoadRuleScorer(path string) (Scorer, error) {
+	w := DefaultRuleWeights()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read rule weights: %w", err)
+		}
+		if err := json.Unmarshal(data, &w); err != nil {
+			return nil, fmt.Errorf("parse rule weights: %w", err)
+		}
+	}
+	return NewRuleScorer(w)
+}
+
+// Weights returns the normalised weights in use
+func (r *RuleScorer) Weights() RuleWeights {
+	return r.w
+}
+
+// MaxScore implements Scorer
+func (r *RuleScorer) MaxScore() float64 {
+	return r.w.TotalScore + r.w.Traits + r.w.Distance
+}
+
+// Score implements Scorer
+func (r *RuleScorer) Score(viewer, cand User) ScoreResult {
+	comps := make([]ScoreComponent, 0, 6)
+	add := func(name string, similarity, weight float64) {
+		comps = append(comps, ScoreComponent{
+			Name:         name,
+			Similarity:   similarity,
+			Weight:       weight,
+			Contribution: similarity * weight,
+		})
+	}
+
+	// 1️⃣ Score difference in total_score
+	add(ComponentTotalScore, similarity01(viewer.TotalScore, cand.TotalScore), r.w.TotalScore)
+
+	// 2️⃣ Individual personality traits, each taking its share of the traits weight
+	add(ComponentPersonality, similarity01(viewer.Personality, cand.Personality), r.w.Traits*r.w.Personality)
+	add(ComponentCommunication, similarity01(viewer.Communication, cand.Communication), r.w.Traits*r.w.Communication)
+	add(ComponentEmotional, similarity01(viewer.Emotional, cand.Emotional), r.w.Traits*r.w.Emotional)
+	add(ComponentConfidence, similarity01(viewer.Confidence, cand.Confidence), r.w.Traits*r.w.Confidence)
+
+	// 3️⃣ Geo-distance
+	d := unknownDistanceSimilarity
+	if km, ok := DistanceKm(viewer, cand); ok {
+		d = distanceSimilarity(km, r.w.DistanceFalloffKm)
+	}
+	add(ComponentDistance, d, r.w.Distance)
+
+	return newScoreResult(viewer, cand, comps)
+}
 
 // similarity01 returns a similarity score between 0–1
 func similarity01(a, b int) float64 {
@@ -18,28 +176,3 @@ func clamp01(x float64) float64 {
 	}
 	return x
 }
-
-// RuleScore computes compatibility between viewer and candidate
-func RuleScore(viewer, cand User, maxDistanceKm float64) (float64, []string) {
-	score := 0.0
-	reasons := []string{}
-
-	// 1️⃣ Score difference in total_score
-	diff := math.Abs(float64(viewer.TotalScore - cand.TotalScore))
-	score += (1 - diff/100.0) * 35
-	reasons = append(reasons, "total_score_proximity")
-
-	// 2️⃣ Individual personality traits
-	p := 0.0
-	p += 0.3 * similarity01(viewer.Personality, cand.Personality)
-	p += 0.3 * similarity01(viewer.Communication, cand.Communication)
-	p += 0.2 * similarity01(viewer.Emotional, cand.Emotional)
-	p += 0.2 * similarity01(viewer.Confidence, cand.Confidence)
-	score += p * 40
-	reasons = append(reasons, "traits_match")
-
-	// 3️⃣ Optional: Geo-distance (future improvement)
-	// We’ll ignore lat/lon for now
-
-	return score, reasons
-}
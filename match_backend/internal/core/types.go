@@ -7,35 +7,91 @@ type User struct {
 	Gender        string   `json:"gender"` // 'M' or 'F'
 	Age           int      `json:"age"`
 	City          string   `json:"city"`
-	Lat, Lon      float64  `json:"lat,omitempty"`
+	Lat           float64  `json:"-"` // never sent; others see Candidate.DistanceKm
+	Lon           float64  `json:"-"`
 	TotalScore    int      `json:"total_score"`
 	Personality   int      `json:"personality"`
 	Communication int      `json:"communication"`
 	Emotional     int      `json:"emotional"`
 	Confidence    int      `json:"confidence"`
 	Images        []string `json:"images,omitempty"`
+
+	// Preferences holds what this user is looking for, when they saved any.
+	// It is used for reciprocal filtering and never sent to other users.
+	Preferences *Preferences `json:"-"`
+}
+
+// Preferences is what a user wants in a match; zero values mean "any"
+type Preferences struct {
+	TargetGender  string  `json:"target_gender,omitempty"` // 'M' or 'F'
+	AgeMin        int     `json:"age_min,omitempty"`
+	AgeMax        int     `json:"age_max,omitempty"`
+	MaxDistanceKm float64 `json:"max_distance_km,omitempty"`
+	MinScore      int     `json:"min_score,omitempty"`
+}
+
+// Accepts reports whether u satisfies these preferences.
+// distanceKm is nil when the distance to u is unknown, which never rejects.
+func (p Preferences) Accepts(u User, distanceKm *float64) bool {
+	if p.TargetGender != "" && p.TargetGender != u.Gender {
+		return false
+	}
+	if p.AgeMin > 0 && u.Age < p.AgeMin {
+		return false
+	}
+	if p.AgeMax > 0 && u.Age > p.AgeMax {
+		return false
+	}
+	if p.MinScore > 0 && u.TotalScore < p.MinScore {
+		return false
+	}
+	if p.MaxDistanceKm > 0 && distanceKm != nil && *distanceKm > p.MaxDistanceKm {
+		return false
+	}
+	return true
+}
+
+// MatchPrefs turns saved preferences into recommendation filters
+func (p Preferences) MatchPrefs() MatchPrefs {
+	var gender rune
+	if p.TargetGender != "" {
+		gender = []rune(p.TargetGender)[0]
+	}
+	return MatchPrefs{
+		TargetGender:  gender,
+		AgeMin:        p.AgeMin,
+		AgeMax:        p.AgeMax,
+		MinScore:      p.MinScore,
+		MaxDistanceKm: p.MaxDistanceKm,
+	}
 }
 
 // MatchPrefs stores filters and preferences
 type MatchPrefs struct {
-	TargetGender rune
-	AgeMin       int
-	AgeMax       int
-	MinScore     int
-	CursorID     int64 // for pagination
-	Limit        int
+	TargetGender  rune
+	AgeMin        int
+	AgeMax        int
+	MinScore      int
+	MaxDistanceKm float64 // 0 means no distance limit
+	Cursor        string  // opaque rank cursor from Recommendation.NextCursor
+	Limit         int
+	Diversity     float64 // 0–1; trades score for variety in city, age and traits
+	ViewerID      int64   // set by Matcher; used for exclusions and reciprocal filtering
+	PoolSize      int     // optional cap on candidates scored, lowest IDs first; 0 scores all
 }
 
 // Candidate wraps a user with a calculated score
 type Candidate struct {
-	User       User     `json:"user"`
-	Score      float64  `json:"score"`
-	Reasons    []string `json:"reasons"`
-	MatchScore int      `json:"match_score"` // Percentage compatibility
+	User       User             `json:"user"`
+	Score      float64          `json:"score"`
+	Reasons    []string         `json:"reasons"`               // human-readable, strongest first
+	Breakdown  []ScoreComponent `json:"breakdown"`             // per-component contributions to Score
+	MatchScore int              `json:"match_score"`           // Percentage compatibility
+	DistanceKm *float64         `json:"distance_km,omitempty"` // nil when either side has no location
 }
 
 // Recommendation is the final response
 type Recommendation struct {
 	Candidates []Candidate `json:"candidates"`
-	NextCursor int64       `json:"next_cursor"`
+	NextCursor string      `json:"next_cursor"` // empty on the last page
 }
@@ -0,0 +1,170 @@
+// Package eval replays historical match outcomes against the matcher to
+// measure how well a scorer ranks the people users went on to like.
+package eval
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/rishyym0927/match_backend/internal/core"
+	"github.com/rishyym0927/match_backend/internal/repo"
+)
+
+// Options configures a replay
+type Options struct {
+	K        int       // cut-off for the @k metrics
+	Since    time.Time // requests from here on are the test set
+	PoolSize int       // cap on candidates scored per viewer, 0 to score all
+}
+
+// Report is the JSON-friendly result of a replay
+type Report struct {
+	Scorer       string    `json:"scorer"`
+	ScorerConfig string    `json:"scorer_config,omitempty"`
+	K            int       `json:"k"`
+	Since        time.Time `json:"since"`
+	GeneratedAt  time.Time `json:"generated_at"`
+
+	Viewers      int `json:"viewers"`       // viewers with at least one positive outcome
+	TestRequests int `json:"test_requests"` // decided requests in the test window
+
+	PrecisionAtK float64 `json:"precision_at_k"`
+	RecallAtK    float64 `json:"recall_at_k"`
+	NDCGAtK      float64 `json:"ndcg_at_k"`
+
+	// Acceptance rate of test requests whose receiver was in the sender's
+	// top k, compared to the rate across all test requests
+	RecommendedAcceptanceRate float64 `json:"recommended_acceptance_rate"`
+	BaselineAcceptanceRate    float64 `json:"baseline_acceptance_rate"`
+	AcceptanceLift            float64 `json:"acceptance_lift"`
+}
+
+// viewerCase is one sender's test period
+type viewerCase struct {
+	at       time.Time      // when the sender's first test request was made
+	positive map[int64]bool // people they liked who liked them back
+	decided  map[int64]bool // receiver -> accepted, for decided requests
+}
+
+// Run replays every sender with requests since opts.Since. For each one
+// the matcher ranks candidates as of their first test request, and the
+// top k are compared with the requests they actually made afterwards.
+func Run(ctx context.Context, h repo.EvalHistory, scorer core.Scorer, opts Options) (Report, error) {
+	rep := Report{K: opts.K, Since: opts.Since, GeneratedAt: time.Now().UTC()}
+	cases := buildCases(h, opts.Since)
+
+	accepted, decided := 0, 0
+	recAccepted, recDecided := 0, 0
+	var precision, recall, ndcg float64
+
+	viewerIDs := make([]int64, 0, len(cases))
+	for id := range cases {
+		viewerIDs = append(viewerIDs, id)
+	}
+	sort.Slice(viewerIDs, func(i, j int) bool { return viewerIDs[i] < viewerIDs[j] })
+
+	for _, viewerID := range viewerIDs {
+		vc := cases[viewerID]
+		for _, ok := range vc.decided {
+			decided++
+			if ok {
+				accepted++
+			}
+		}
+
+		matcher := core.NewMatcher(newSnapshot(h, viewerID, vc.at), scorer, nil)
+		rec, err := matcher.Recommend(ctx, viewerID, core.MatchPrefs{Limit: opts.K, PoolSize: opts.PoolSize})
+		if err != nil {
+			return rep, err
+		}
+
+		hits := 0
+		dcg := 0.0
+		for i, c := range rec.Candidates {
+			if ok, seen := vc.decided[c.User.ID]; seen {
+				recDecided++
+				if ok {
+					recAccepted++
+				}
+			}
+			if vc.positive[c.User.ID] {
+				hits++
+				dcg += 1 / math.Log2(float64(i+2))
+			}
+		}
+
+		if len(vc.positive) == 0 {
+			continue
+		}
+		rep.Viewers++
+		precision += float64(hits) / float64(opts.K)
+		recall += float64(hits) / float64(len(vc.positive))
+		ndcg += dcg / idealDCG(len(vc.positive), opts.K)
+	}
+
+	rep.TestRequests = decided
+	if rep.Viewers > 0 {
+		n := float64(rep.Viewers)
+		rep.PrecisionAtK = precision / n
+		rep.RecallAtK = recall / n
+		rep.NDCGAtK = ndcg / n
+	}
+	if decided > 0 {
+		rep.BaselineAcceptanceRate = float64(accepted) / float64(decided)
+	}
+	if recDecided > 0 {
+		rep.RecommendedAcceptanceRate = float64(recAccepted) / float64(recDecided)
+	}
+	if rep.BaselineAcceptanceRate > 0 {
+		rep.AcceptanceLift = rep.RecommendedAcceptanceRate / rep.BaselineAcceptanceRate
+	}
+
+	return rep, nil
+}
+
+// buildCases groups test-window requests by sender. A receiver counts as
+// a positive when the request was accepted or the pair matched later.
+func buildCases(h repo.EvalHistory, since time.Time) map[int64]*viewerCase {
+	cases := map[int64]*viewerCase{}
+	for _, r := range h.Requests {
+		if r.CreatedAt.Before(since) || (r.Status != "accepted" && r.Status != "rejected") {
+			continue
+		}
+		vc, ok := cases[r.SenderID]
+		if !ok {
+			vc = &viewerCase{at: r.CreatedAt, positive: map[int64]bool{}, decided: map[int64]bool{}}
+			cases[r.SenderID] = vc
+		}
+		if r.CreatedAt.Before(vc.at) {
+			vc.at = r.CreatedAt
+		}
+		vc.decided[r.ReceiverID] = r.Status == "accepted"
+		if r.Status == "accepted" {
+			vc.positive[r.ReceiverID] = true
+		}
+	}
+
+	for _, m := range h.Matches {
+		for _, pair := range [][2]int64{{m.User1ID, m.User2ID}, {m.User2ID, m.User1ID}} {
+			if vc, ok := cases[pair[0]]; ok && !m.MatchedAt.Before(vc.at) {
+				vc.positive[pair[1]] = true
+			}
+		}
+	}
+
+	return cases
+}
+
+// idealDCG is the DCG of a ranking with every positive at the top
+func idealDCG(positives, k int) float64 {
+	if positives > k {
+		positives = k
+	}
+	dcg := 0.0
+	for i := 0; i < positives; i++ {
+		dcg += 1 / math.Log2(float64(i+2))
+	}
+	return dcg
+}
@@ -0,0 +1,85 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/rishyym0927/match_backend/internal/core"
+	"github.com/rishyym0927/match_backend/internal/repo"
+)
+
+// snapshot is a read-only core.UserRepo showing the data as it was at
+// a point in time: only users who had signed up, and only the swipes
+// the viewer had made, before then
+type snapshot struct {
+	users      map[int64]repo.EvalUser
+	exclusions map[int64]struct{}
+}
+
+// newSnapshot builds the view of history that viewerID had at time at
+func newSnapshot(h repo.EvalHistory, viewerID int64, at time.Time) *snapshot {
+	s := &snapshot{
+		users:      make(map[int64]repo.EvalUser, len(h.Users)),
+		exclusions: map[int64]struct{}{},
+	}
+	for _, u := range h.Users {
+		if !u.CreatedAt.After(at) {
+			s.users[u.ID] = u
+		}
+	}
+	for _, r := range h.Requests {
+		if r.SenderID == viewerID && r.CreatedAt.Before(at) {
+			s.exclusions[r.ReceiverID] = struct{}{}
+		}
+	}
+	return s
+}
+
+// GetUser implements core.UserRepo
+func (s *snapshot) GetUser(_ context.Context, id int64) (core.User, error) {
+	u, ok := s.users[id]
+	if !ok {
+		return core.User{}, errors.New("user not found in snapshot")
+	}
+	return u.User, nil
+}
+
+// FetchCandidates implements core.UserRepo with the same filters as the
+// Postgres query, lowest IDs first
+func (s *snapshot) FetchCandidates(_ context.Context, prefs core.MatchPrefs) ([]core.User, error) {
+	var out []core.User
+	for id, u := range s.users {
+		if id == prefs.ViewerID {
+			continue
+		}
+		if _, skip := s.exclusions[id]; skip {
+			continue
+		}
+		if prefs.TargetGender != 0 && u.Gender != string(prefs.TargetGender) {
+			continue
+		}
+		if prefs.AgeMin > 0 && u.Age < prefs.AgeMin {
+			continue
+		}
+		if prefs.AgeMax > 0 && u.Age > prefs.AgeMax {
+			continue
+		}
+		if prefs.MinScore > 0 && u.TotalScore < prefs.MinScore {
+			continue
+		}
+		out = append(out, u.User)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	if prefs.PoolSize > 0 && len(out) > prefs.PoolSize {
+		out = out[:prefs.PoolSize]
+	}
+	return out, nil
+}
+
+// GetUserImageURLsBatch implements core.UserRepo; images don't affect ranking
+func (s *snapshot) GetUserImageURLsBatch(_ context.Context, userIDs []int64) (map[int64][]string, error) {
+	return map[int64][]string{}, nil
+}
@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/rishyym0927/match_backend/internal/core"
+	"github.com/rishyym0927/match_backend/internal/repo"
+)
+
+// DailyPicks precomputes each user's "Top picks today"
+type DailyPicks struct {
+	Repo    *repo.Postgres
+	Matcher *core.Matcher
+	Count   int
+}
+
+// Today returns the current pick date; picks roll over at midnight UTC
+func Today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+// Run generates today's picks for every user who doesn't have them yet
+func (d *DailyPicks) Run(ctx context.Context) error {
+	day := Today()
+
+	userIDs, err := d.Repo.ListUsersWithoutDailyPicks(ctx, day)
+	if err != nil {
+		return err
+	}
+
+	generated := 0
+	for _, uid := range userIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, err := d.Generate(ctx, uid, day); err != nil {
+			log.Printf("daily picks for user %d failed: %v", uid, err)
+			continue
+		}
+		generated++
+	}
+
+	if generated > 0 {
+		log.Printf("📅 Generated daily picks for %d users", generated)
+	}
+	return nil
+}
+
+// Generate computes and stores one user's picks for day, honouring
+// their saved preferences
+func (d *DailyPicks) Generate(ctx context.Context, userID int64, day time.Time) ([]core.Candidate, error) {
+	saved, err := d.Repo.GetUserPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs := saved.MatchPrefs()
+	prefs.Limit = d.Count
+
+	rec, err := d.Matcher.Recommend(ctx, userID, prefs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.Repo.SaveDailyPicks(ctx, userID, day, rec.Candidates); err != nil {
+		return nil, err
+	}
+	return rec.Candidates, nil
+}
@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/rishyym0927/match_backend/internal/repo"
+)
+
+// ExpireRequests expires pending match requests nobody answered in time
+type ExpireRequests struct {
+	Repo *repo.Postgres
+	TTL  time.Duration
+}
+
+// Run expires every pending request older than TTL
+func (e *ExpireRequests) Run(ctx context.Context) error {
+	n, err := e.Repo.ExpireMatchRequests(ctx, e.TTL)
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		log.Printf("⌛ Expired %d match requests", n)
+	}
+	return nil
+}
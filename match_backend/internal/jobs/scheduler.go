@@ -0,0 +1,43 @@
+// Package jobs runs the server's periodic background work.
+package jobs
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Scheduler runs periodic jobs until their context is cancelled
+type Scheduler struct {
+	wg sync.WaitGroup
+}
+
+// Every runs fn right away and then once per interval in its own
+// goroutine. Errors are logged and the job keeps its schedule.
+func (s *Scheduler) Every(ctx context.Context, name string, interval time.Duration, fn func(context.Context) error) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := fn(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("job %s failed: %v", name, err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Wait blocks until every job has returned after cancellation
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
@@ -2,7 +2,11 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
 type MessageRow struct {
@@ -11,30 +15,141 @@ type MessageRow struct {
 	SenderID int64
 	Body string
 	SentAt time.Time
+	Read bool // whether the recipient has read it
+}
+
+// IsMatchParticipant reports whether the user is one of the two users in
+// the match. A match that doesn't exist has no participants.
+func (p *Postgres) IsMatchParticipant(ctx context.Context, matchID, userID int64) (bool, error) {
+	var ok bool
+	q := `SELECT EXISTS (SELECT 1 FROM matches WHERE id=$1 AND (user1_id=$2 OR user2_id=$2));`
+	err := p.Pool.QueryRow(ctx, q, matchID, userID).Scan(&ok)
+	return ok, err
+}
+
+// InsertMessage stores a message in an active match and returns it along
+// with the other participant's ID. It returns ErrMatchNotFound or
+// ErrMatchEnded when the match can't take messages.
+func (p *Postgres) InsertMessage(ctx context.Context, matchID, senderID int64, body string) (MessageRow, int64, error) {
+	m := MessageRow{MatchID: matchID, SenderID: senderID, Body: body}
+	var recipientID int64
+	q := `INSERT INTO messages (match_id, sender_id, body)
+	      SELECT id, $2, $3 FROM matches WHERE id=$1 AND unmatched_at IS NULL
+	      RETURNING id, sent_at,
+	        (SELECT CASE WHEN user1_id=$2 THEN user2_id ELSE user1_id END FROM matches WHERE id=$1);`
+	err := p.Pool.QueryRow(ctx, q, matchID, senderID, body).Scan(&m.ID, &m.SentAt, &recipientID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var exists bool
+		if err := p.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM matches WHERE id=$1)`, matchID).Scan(&exists); err != nil {
+			return m, 0, err
+		}
+		if !exists {
+			return m, 0, ErrMatchNotFound
+		}
+		return m, 0, ErrMatchEnded
+	}
+	return m, recipientID, err
 }
 
-func (p *Postgres) InsertMessage(ctx context.Context, matchID, senderID int64, body string) (int64, error) {
-	var id int64
-	q := `INSERT INTO messages (match_id, sender_id, body) VALUES ($1,$2,$3) RETURNING id;`
-	err := p.Pool.QueryRow(ctx, q, matchID, senderID, body).Scan(&id)
-	return id, err
+// ErrInvalidMessageCursor means a before/after ID isn't a message in the match
+var ErrInvalidMessageCursor = errors.New("invalid message cursor")
+
+// MessagePage selects a page of a match's history. With neither cursor
+// set it's the newest messages.
+type MessagePage struct {
+	BeforeID int64 // messages older than this one
+	AfterID  int64 // messages newer than this one
+	Limit    int
 }
 
-func (p *Postgres) GetMessages(ctx context.Context, matchID int64, limit int) ([]MessageRow, error) {
+// GetMessages returns a page of messages newest first, and whether more
+// exist beyond it in the direction of travel. Paging is keyset on
+// (sent_at, id) so idx_messages_match serves it however deep the page.
+func (p *Postgres) GetMessages(ctx context.Context, matchID int64, page MessagePage) ([]MessageRow, bool, error) {
+	limit := page.Limit
 	if limit <= 0 || limit > 200 { limit = 100 }
-	q := `SELECT id, match_id, sender_id, body, sent_at 
-	      FROM messages WHERE match_id=$1 ORDER BY sent_at ASC LIMIT $2;`
-	rows, err := p.Pool.Query(ctx, q, matchID, limit)
-	if err != nil { return nil, err }
+
+	q := `SELECT msg.id, msg.match_id, msg.sender_id, msg.body, msg.sent_at,
+	             msg.id <= COALESCE(CASE WHEN msg.sender_id=m.user1_id
+	                                     THEN m.user2_last_read_message_id
+	                                     ELSE m.user1_last_read_message_id END, 0) AS read
+	      FROM messages msg JOIN matches m ON m.id = msg.match_id
+	      WHERE msg.match_id=$1 AND msg.deleted_at IS NULL`
+	args := []any{matchID, limit + 1}
+
+	// Newer pages are read oldest first so they continue from the cursor
+	// without gaps, then flipped
+	ascending := page.AfterID > 0
+	anchorID := page.BeforeID
+	if ascending {
+		anchorID = page.AfterID
+	}
+
+	if anchorID > 0 {
+		var anchorAt time.Time
+		err := p.Pool.QueryRow(ctx, `SELECT sent_at FROM messages WHERE id=$1 AND match_id=$2;`, anchorID, matchID).Scan(&anchorAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, false, ErrInvalidMessageCursor
+		}
+		if err != nil { return nil, false, err }
+
+		op := "<"
+		if ascending { op = ">" }
+		q += ` AND (msg.sent_at, msg.id) ` + op + ` ($3, $4)`
+		args = append(args, anchorAt, anchorID)
+	}
+
+	if ascending {
+		q += ` ORDER BY msg.sent_at ASC, msg.id ASC LIMIT $2;`
+	} else {
+		q += ` ORDER BY msg.sent_at DESC, msg.id DESC LIMIT $2;`
+	}
+
+	rows, err := p.Pool.Query(ctx, q, args...)
+	if err != nil { return nil, false, err }
 	defer rows.Close()
 
 	out := []MessageRow{}
 	for rows.Next() {
 		var m MessageRow
-		if err := rows.Scan(&m.ID, &m.MatchID, &m.SenderID, &m.Body, &m.SentAt); err != nil {
-			return nil, err
+		if err := rows.Scan(&m.ID, &m.MatchID, &m.SenderID, &m.Body, &m.SentAt, &m.Read); err != nil {
+			return nil, false, err
 		}
 		out = append(out, m)
 	}
-	return out, rows.Err()
+	if err := rows.Err(); err != nil { return nil, false, err }
+
+	more := len(out) > limit
+	if more { out = out[:limit] }
+	if ascending { slices.Reverse(out) }
+	return out, more, nil
+}
+
+// MarkMessagesRead moves the user's read marker in a match forward to
+// messageID, or to the latest message when messageID is 0. The marker
+// never moves back. It returns the new marker and the other user's ID.
+func (p *Postgres) MarkMessagesRead(ctx context.Context, matchID, userID, messageID int64) (int64, int64, error) {
+	var lastRead, peerID int64
+	q := `WITH latest AS (
+	        SELECT COALESCE(MAX(id), 0) AS id FROM messages WHERE match_id=$1 AND deleted_at IS NULL
+	      ), target AS (
+	        SELECT CASE WHEN $3::bigint > 0 THEN LEAST($3::bigint, latest.id) ELSE latest.id END AS id FROM latest
+	      )
+	      UPDATE matches m SET
+	        user1_last_read_message_id = CASE WHEN m.user1_id=$2
+	          THEN GREATEST(COALESCE(m.user1_last_read_message_id, 0), target.id)
+	          ELSE m.user1_last_read_message_id END,
+	        user2_last_read_message_id = CASE WHEN m.user2_id=$2
+	          THEN GREATEST(COALESCE(m.user2_last_read_message_id, 0), target.id)
+	          ELSE m.user2_last_read_message_id END
+	      FROM target
+	      WHERE m.id=$1 AND (m.user1_id=$2 OR m.user2_id=$2)
+	      RETURNING
+	        CASE WHEN m.user1_id=$2 THEN m.user1_last_read_message_id ELSE m.user2_last_read_message_id END,
+	        CASE WHEN m.user1_id=$2 THEN m.user2_id ELSE m.user1_id END;`
+	err := p.Pool.QueryRow(ctx, q, matchID, userID, messageID).Scan(&lastRead, &peerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, 0, ErrNotMatchParticipant
+	}
+	return lastRead, peerID, err
 }
@@ -0,0 +1,153 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/rishyym0927/match_backend/internal/core"
+)
+
+// DailyPick is one precomputed "Top picks today" candidate
+type DailyPick struct {
+	UserID     int64    `json:"user_id"`
+	Name       string   `json:"name"`
+	Age        int      `json:"age"`
+	Location   string   `json:"location"`
+	Image      string   `json:"image"`
+	MatchScore int      `json:"match_score"`
+	Reasons    []string `json:"reasons"`
+	Rank       int      `json:"rank"`
+}
+
+// SaveDailyPicks replaces a user's picks for the given day and marks the
+// day as generated, even when there are no picks
+func (p *Postgres) SaveDailyPicks(ctx context.Context, userID int64, day time.Time, picks []core.Candidate) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DELETE FROM daily_picks WHERE user_id = $1 AND pick_date = $2`, userID, day)
+	if err != nil {
+		return err
+	}
+
+	for i, c := range picks {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO daily_picks (user_id, pick_date, candidate_id, rank, score, match_score, reasons)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, userID, day, c.User.ID, i+1, c.Score, c.MatchScore, c.Reasons)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO daily_pick_runs (user_id, pick_date)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, pick_date) DO UPDATE SET generated_at = NOW()
+	`, userID, day)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetDailyPicks returns a user's picks for the given day, leaving out
+// anyone they have swiped on since
+func (p *Postgres) GetDailyPicks(ctx context.Context, userID int64, day time.Time) ([]DailyPick, error) {
+	query := `
+		SELECT 
+			dp.candidate_id,
+			u.name,
+			COALESCE(u.age, 0) AS age,
+			COALESCE(u.city, '') AS city,
+			COALESCE(
+				(SELECT public_url FROM user_images WHERE user_id = u.user_id AND is_primary = true LIMIT 1),
+				(SELECT public_url FROM user_images WHERE user_id = u.user_id ORDER BY uploaded_at DESC LIMIT 1),
+				''
+			) AS image,
+			dp.match_score,
+			COALESCE(dp.reasons, '{}') AS reasons,
+			dp.rank
+		FROM daily_picks dp
+		INNER JOIN users u ON dp.candidate_id = u.user_id
+		WHERE dp.user_id = $1 
+		  AND dp.pick_date = $2
+		  AND NOT EXISTS (
+			SELECT 1 FROM user_exclusions e
+			WHERE (e.user_id = dp.user_id AND e.target_id = dp.candidate_id)
+			   OR (e.user_id = dp.candidate_id AND e.target_id = dp.user_id AND e.reason = 'blocked')
+		  )
+		ORDER BY dp.rank
+	`
+
+	rows, err := p.Pool.Query(ctx, query, userID, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	picks := []DailyPick{}
+	for rows.Next() {
+		var pick DailyPick
+		if err := rows.Scan(
+			&pick.UserID, &pick.Name, &pick.Age, &pick.Location, &pick.Image,
+			&pick.MatchScore, &pick.Reasons, &pick.Rank,
+		); err != nil {
+			return nil, err
+		}
+
+		// Set default image if empty
+		if pick.Image == "" {
+			pick.Image = "/default.jpg"
+		}
+
+		picks = append(picks, pick)
+	}
+
+	return picks, rows.Err()
+}
+
+// HasDailyPicks reports whether picks were already generated for the day
+func (p *Postgres) HasDailyPicks(ctx context.Context, userID int64, day time.Time) (bool, error) {
+	var exists bool
+	err := p.Pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM daily_pick_runs WHERE user_id = $1 AND pick_date = $2)`,
+		userID, day).Scan(&exists)
+	return exists, err
+}
+
+// ListUsersWithoutDailyPicks returns users who have scores but no picks
+// for the given day yet
+func (p *Postgres) ListUsersWithoutDailyPicks(ctx context.Context, day time.Time) ([]int64, error) {
+	query := `
+		SELECT u.user_id
+		FROM users u
+		INNER JOIN scores s ON u.user_id = s.user_id
+		WHERE NOT EXISTS (
+			SELECT 1 FROM daily_pick_runs dr
+			WHERE dr.user_id = u.user_id AND dr.pick_date = $1
+		)
+		ORDER BY u.user_id
+	`
+
+	rows, err := p.Pool.Query(ctx, query, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -0,0 +1,114 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/rishyym0927/match_backend/internal/core"
+)
+
+// EvalUser is a user with their sign-up time, for point-in-time replays
+type EvalUser struct {
+	core.User
+	CreatedAt time.Time
+}
+
+// EvalRequest is one historical match request
+type EvalRequest struct {
+	SenderID   int64
+	ReceiverID int64
+	Status     string
+	CreatedAt  time.Time
+}
+
+// EvalMatch is one historical match
+type EvalMatch struct {
+	User1ID   int64
+	User2ID   int64
+	MatchedAt time.Time
+}
+
+// EvalHistory is everything the offline evaluator replays
+type EvalHistory struct {
+	Users    []EvalUser
+	Requests []EvalRequest
+	Matches  []EvalMatch
+}
+
+// LoadEvalHistory reads users, match requests and matches for offline
+// evaluation. Trait scores are current values; they carry no history.
+func (p *Postgres) LoadEvalHistory(ctx context.Context) (EvalHistory, error) {
+	var h EvalHistory
+
+	userRows, err := p.Pool.Query(ctx, `
+		SELECT 
+			u.user_id, u.name, COALESCE(u.gender, ''), COALESCE(u.age, 0), COALESCE(u.city, ''),
+			COALESCE(u.lat, 0.0), COALESCE(u.lon, 0.0),
+			COALESCE(s.total_score, 0), COALESCE(s.personality, 0), COALESCE(s.communication, 0),
+			COALESCE(s.emotional, 0), COALESCE(s.confidence, 0),
+			COALESCE(u.created_at, 'epoch'::timestamp)
+		FROM users u
+		LEFT JOIN scores s ON u.user_id = s.user_id
+		ORDER BY u.user_id
+	`)
+	if err != nil {
+		return h, err
+	}
+	defer userRows.Close()
+
+	for userRows.Next() {
+		var u EvalUser
+		if err := userRows.Scan(
+			&u.ID, &u.Name, &u.Gender, &u.Age, &u.City, &u.Lat, &u.Lon,
+			&u.TotalScore, &u.Personality, &u.Communication, &u.Emotional, &u.Confidence,
+			&u.CreatedAt,
+		); err != nil {
+			return h, err
+		}
+		h.Users = append(h.Users, u)
+	}
+	if err := userRows.Err(); err != nil {
+		return h, err
+	}
+
+	reqRows, err := p.Pool.Query(ctx, `
+		SELECT sender_id, receiver_id, COALESCE(status, 'pending'), COALESCE(created_at, 'epoch'::timestamp)
+		FROM match_requests
+		ORDER BY created_at, id
+	`)
+	if err != nil {
+		return h, err
+	}
+	defer reqRows.Close()
+
+	for reqRows.Next() {
+		var r EvalRequest
+		if err := reqRows.Scan(&r.SenderID, &r.ReceiverID, &r.Status, &r.CreatedAt); err != nil {
+			return h, err
+		}
+		h.Requests = append(h.Requests, r)
+	}
+	if err := reqRows.Err(); err != nil {
+		return h, err
+	}
+
+	matchRows, err := p.Pool.Query(ctx, `
+		SELECT user1_id, user2_id, COALESCE(matched_at, 'epoch'::timestamp)
+		FROM matches
+		ORDER BY matched_at, id
+	`)
+	if err != nil {
+		return h, err
+	}
+	defer matchRows.Close()
+
+	for matchRows.Next() {
+		var m EvalMatch
+		if err := matchRows.Scan(&m.User1ID, &m.User2ID, &m.MatchedAt); err != nil {
+			return h, err
+		}
+		h.Matches = append(h.Matches, m)
+	}
+
+	return h, matchRows.Err()
+}
@@ -2,8 +2,10 @@ package repo
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/rishyym0927/match_backend/internal/core"
 )
 
@@ -31,22 +33,28 @@ func (p *Postgres) FetchExclusions(ctx context.Context, viewer int64) (map[int64
 	return exclusions, rows.Err()
 }
 
+// addExclusionSQL upserts an exclusion; a block or unmatch is never
+// overwritten, since both keep the pair apart for good
+const addExclusionSQL = `
+	INSERT INTO user_exclusions (user_id, target_id, reason)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (user_id, target_id) 
+	DO UPDATE SET reason = EXCLUDED.reason, created_at = NOW()
+	WHERE user_exclusions.reason NOT IN ('blocked', 'unmatched')
+`
+
 // AddExclusion adds a user to the exclusion list
 func (p *Postgres) AddExclusion(ctx context.Context, userID, targetID int64, reason string) error {
-	query := `
-		INSERT INTO user_exclusions (user_id, target_id, reason)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (user_id, target_id) 
-		DO UPDATE SET reason = EXCLUDED.reason, created_at = NOW()
-	`
-	_, err := p.Pool.Exec(ctx, query, userID, targetID, reason)
+	_, err := p.Pool.Exec(ctx, addExclusionSQL, userID, targetID, reason)
 	return err
 }
 
-// FetchCandidates retrieves potential matches based on preferences
-func (p *Postgres) FetchCandidates(ctx context.Context, prefs core.MatchPrefs) ([]core.User, int64, error) {
+// FetchCandidates retrieves the pool of potential matches to be scored.
+// Paging happens after scoring in core.Matcher, so this returns every
+// user that passes the filters and isn't excluded, or the prefs.PoolSize
+// lowest IDs among them when a cap is set.
+func (p *Postgres) FetchCandidates(ctx context.Context, prefs core.MatchPrefs) ([]core.User, error) {
 	var users []core.User
-	var nextCursor int64
 
 	// Build dynamic query based on provided filters
 	query := `
@@ -62,14 +70,34 @@ func (p *Postgres) FetchCandidates(ctx context.Context, prefs core.MatchPrefs) (
 			COALESCE(s.personality, 0) AS personality,
 			COALESCE(s.communication, 0) AS communication,
 			COALESCE(s.emotional, 0) AS emotional,
-			COALESCE(s.confidence, 0) AS confidence
+			COALESCE(s.confidence, 0) AS confidence,
+			cp.user_id IS NOT NULL AS has_prefs,
+			COALESCE(cp.target_gender, '') AS pref_gender,
+			COALESCE(cp.age_min, 0) AS pref_age_min,
+			COALESCE(cp.age_max, 0) AS pref_age_max,
+			COALESCE(cp.max_distance_km, 0) AS pref_max_distance_km,
+			COALESCE(cp.min_score, 0) AS pref_min_score
 		FROM users u
 		LEFT JOIN scores s ON u.user_id = s.user_id
-		WHERE 1=1
+		LEFT JOIN user_preferences cp ON u.user_id = cp.user_id
+		JOIN users v ON v.user_id = $1
+		LEFT JOIN scores vs ON v.user_id = vs.user_id
+		WHERE u.user_id <> $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM user_exclusions e
+			WHERE (e.user_id = $1 AND e.target_id = u.user_id)
+			   OR (e.user_id = u.user_id AND e.target_id = $1 AND e.reason = 'blocked')
+		  )
+		  -- Reciprocal filter: the viewer must fit the candidate's preferences.
+		  -- Distance is checked by the matcher once it has computed it.
+		  AND (cp.target_gender IS NULL OR cp.target_gender = v.gender)
+		  AND (cp.age_min IS NULL OR v.age >= cp.age_min)
+		  AND (cp.age_max IS NULL OR v.age <= cp.age_max)
+		  AND (cp.min_score IS NULL OR COALESCE(vs.total_score, 0) >= cp.min_score)
 	`
 
-	args := []interface{}{}
-	argIndex := 1
+	args := []interface{}{prefs.ViewerID}
+	argIndex := 2
 
 	// Add gender filter if specified
 	if prefs.TargetGender != 0 {
@@ -97,101 +125,243 @@ func (p *Postgres) FetchCandidates(ctx context.Context, prefs core.MatchPrefs) (
 		argIndex++
 	}
 
-	// Add cursor for pagination
-	if prefs.CursorID > 0 {
-		query += ` AND u.user_id > $` + fmt.Sprintf("%d", argIndex)
-		args = append(args, prefs.CursorID)
+	// Cheap prefilter for the viewer's distance limit: a degree of latitude
+	// is at least 111 km, so this never drops anyone the exact haversine
+	// check in the matcher would keep. Users without a location pass.
+	if prefs.MaxDistanceKm > 0 {
+		query += ` AND (
+			(COALESCE(v.lat, 0) = 0 AND COALESCE(v.lon, 0) = 0)
+			OR (COALESCE(u.lat, 0) = 0 AND COALESCE(u.lon, 0) = 0)
+			OR ABS(u.lat - v.lat) * 111.0 <= $` + fmt.Sprintf("%d", argIndex) + `
+		)`
+		args = append(args, prefs.MaxDistanceKm)
 		argIndex++
 	}
 
-	// Order and limit
-	query += ` ORDER BY u.user_id LIMIT $` + fmt.Sprintf("%d", argIndex)
-	args = append(args, prefs.Limit+1) // +1 to detect next cursor
+	// A capped pool must be deterministic so repeated pages rank the same set
+	query += ` ORDER BY u.user_id`
+	if prefs.PoolSize > 0 {
+		query += ` LIMIT $` + fmt.Sprintf("%d", argIndex)
+		args = append(args, prefs.PoolSize)
+	}
 
 	rows, err := p.Pool.Query(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var u core.User
+		var hasPrefs bool
+		var cp core.Preferences
 		if err := rows.Scan(
 			&u.ID, &u.Name, &u.Gender, &u.Age, &u.City, &u.Lat, &u.Lon,
 			&u.TotalScore, &u.Personality, &u.Communication, &u.Emotional, &u.Confidence,
+			&hasPrefs, &cp.TargetGender, &cp.AgeMin, &cp.AgeMax, &cp.MaxDistanceKm, &cp.MinScore,
 		); err != nil {
-			return nil, 0, err
+			return nil, err
+		}
+		if hasPrefs {
+			u.Preferences = &cp
 		}
 		users = append(users, u)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, 0, err
+	return users, rows.Err()
+}
+
+// MatchRequestResult tells the sender what their request led to
+type MatchRequestResult struct {
+	Sent      bool  `json:"-"` // a request was newly created or reopened
+	Matched   bool  `json:"matched"`
+	MatchID   int64 `json:"match_id,omitempty"`
+	Remaining int   `json:"remaining_swipes"`
+}
+
+// SendMatchRequest records a like and creates a new match request,
+// excluding the receiver from the sender's recommendations. If the
+// receiver already has a pending request to the sender, both are
+// accepted and the match is created straight away in the same
+// transaction. It fails with ErrSwipeQuotaExceeded once quota is used up,
+// ErrInvalidTransition if the receiver already answered and ErrUnmatched
+// if the two users matched before and one unmatched.
+func (p *Postgres) SendMatchRequest(ctx context.Context, senderID, receiverID int64, quota SwipeQuota) (MatchRequestResult, error) {
+	var res MatchRequestResult
+
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialise requests between the same two users so simultaneous
+	// likes can't both miss each other and stay pending. The two-key lock
+	// only takes int4s, so the ordered pair is hashed into one bigint key.
+	_, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended(
+		'match_request:' || LEAST($1::bigint, $2::bigint) || ':' || GREATEST($1::bigint, $2::bigint), 0))`,
+		senderID, receiverID)
+	if err != nil {
+		return res, err
+	}
+
+	unmatched, err := hasUnmatched(ctx, tx, senderID, receiverID)
+	if err != nil {
+		return res, err
+	}
+	if unmatched {
+		return res, ErrUnmatched
+	}
+
+	res.Remaining, err = recordSwipe(ctx, tx, senderID, receiverID, "liked", quota)
+	if err != nil {
+		return res, err
+	}
+	if _, err := tx.Exec(ctx, addExclusionSQL, senderID, receiverID, "liked"); err != nil {
+		return res, err
 	}
 
-	// Pagination: If more than limit, record next cursor ID
-	if len(users) > prefs.Limit {
-		nextCursor = users[len(users)-1].ID
-		users = users[:prefs.Limit]
+	ownID, ownStatus, err := lockRequest(ctx, tx, senderID, receiverID)
+	ownExists := err == nil
+	if err != nil && !errors.Is(err, ErrRequestNotFound) {
+		return res, err
 	}
 
-	return users, nextCursor, nil
+	// Accept the receiver's pending request to us, if there is one
+	_, err = transitionRequest(ctx, tx, receiverID, senderID, StatusAccepted)
+	mutual := err == nil
+	if err != nil && !errors.Is(err, ErrRequestNotFound) && !errors.Is(err, ErrInvalidTransition) {
+		return res, err
+	}
+
+	switch {
+	case !ownExists:
+		status := StatusPending
+		if mutual {
+			status = StatusAccepted
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO match_requests (sender_id, receiver_id, status)
+			VALUES ($1, $2, $3)
+		`, senderID, receiverID, status)
+		if err != nil {
+			return res, err
+		}
+		res.Sent = true
+
+	case mutual:
+		// Settle our side too, reopening it first if it was withdrawn or
+		// expired; an earlier answer is left as it was
+		if canTransition(ownStatus, StatusPending) {
+			if err := setRequestStatus(ctx, tx, ownID, ownStatus, StatusPending); err != nil {
+				return res, err
+			}
+			ownStatus = StatusPending
+		}
+		if ownStatus == StatusPending {
+			if err := setRequestStatus(ctx, tx, ownID, ownStatus, StatusAccepted); err != nil {
+				return res, err
+			}
+		}
+
+	case ownStatus == StatusPending:
+		// Liking again leaves the request waiting as it is; the receiver
+		// was already told about it
+
+	default:
+		// Reopen a withdrawn or expired request; an answered one is final
+		// and fails with ErrInvalidTransition
+		if err := setRequestStatus(ctx, tx, ownID, ownStatus, StatusPending); err != nil {
+			return res, err
+		}
+		res.Sent = true
+	}
+
+	if mutual {
+		// The earlier request's sender goes first, as in RespondMatchRequest
+		res.MatchID, err = createMatch(ctx, tx, receiverID, senderID)
+		if err != nil {
+			return res, err
+		}
+		res.Matched = true
+	}
+
+	return res, tx.Commit(ctx)
 }
 
-// SendMatchRequest creates a new match request
-func (p *Postgres) SendMatchRequest(ctx context.Context, senderID, receiverID int64) error {
-	query := `
-		INSERT INTO match_requests (sender_id, receiver_id, status)
-		VALUES ($1, $2, 'pending')
-		ON CONFLICT (sender_id, receiver_id)
-		DO UPDATE SET status = 'pending', created_at = NOW()
-	`
-	_, err := p.Pool.Exec(ctx, query, senderID, receiverID)
-	return err
+// createMatch inserts a match between two users unless one already
+// exists in either order, and returns its ID. An ended match is never
+// revived; it fails with ErrMatchEnded.
+func createMatch(ctx context.Context, tx pgx.Tx, user1ID, user2ID int64) (int64, error) {
+	var id int64
+	var ended bool
+	err := tx.QueryRow(ctx, `
+		SELECT id, unmatched_at IS NOT NULL FROM matches
+		WHERE (user1_id = $1 AND user2_id = $2) OR (user1_id = $2 AND user2_id = $1)
+	`, user1ID, user2ID).Scan(&id, &ended)
+	if err == nil && ended {
+		return 0, ErrMatchEnded
+	}
+	if err == nil || !errors.Is(err, pgx.ErrNoRows) {
+		return id, err
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO matches (user1_id, user2_id)
+		VALUES ($1, $2)
+		RETURNING id
+	`, user1ID, user2ID).Scan(&id)
+	return id, err
 }
 
-// RespondMatchRequest updates the status and creates a match if accepted
-func (p *Postgres) RespondMatchRequest(ctx context.Context, senderID, receiverID int64, accept bool) error {
-	status := "rejected"
+// RespondMatchRequest accepts or rejects a pending request and creates
+// the match if accepted, returning its ID. It returns ErrRequestNotFound
+// when there is no such request, ErrInvalidTransition when it is no
+// longer pending and ErrUnmatched when accepting a user who unmatched
+// before.
+func (p *Postgres) RespondMatchRequest(ctx context.Context, senderID, receiverID int64, accept bool) (int64, error) {
+	status := StatusRejected
 	if accept {
-		status = "accepted"
+		status = StatusAccepted
 	}
 
 	tx, err := p.Pool.Begin(ctx)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer tx.Rollback(ctx)
 
+	if accept {
+		unmatched, err := hasUnmatched(ctx, tx, senderID, receiverID)
+		if err != nil {
+			return 0, err
+		}
+		if unmatched {
+			return 0, ErrUnmatched
+		}
+	}
+
 	// Update request status
-	_, err = tx.Exec(ctx, `
-		UPDATE match_requests
-		SET status = $3
-		WHERE sender_id = $1 AND receiver_id = $2
-	`, senderID, receiverID, status)
-	if err != nil {
-		return err
+	if _, err := transitionRequest(ctx, tx, senderID, receiverID, status); err != nil {
+		return 0, err
 	}
 
+	var matchID int64
 	if accept {
 		// Create mutual match
-		_, err = tx.Exec(ctx, `
-			INSERT INTO matches (user1_id, user2_id)
-			VALUES ($1, $2)
-			ON CONFLICT (user1_id, user2_id) DO NOTHING
-		`, senderID, receiverID)
-		if err != nil {
-			return err
+		if matchID, err = createMatch(ctx, tx, senderID, receiverID); err != nil {
+			return 0, err
 		}
 	}
 
-	return tx.Commit(ctx)
+	return matchID, tx.Commit(ctx)
 }
 
-// MatchRequestResponse represents an incoming match request with user details
+// MatchRequestResponse represents a match request with the other user's details
 type MatchRequestResponse struct {
 	ID            int64    `json:"id"`
 	SenderID      int64    `json:"sender_id"`
+	ReceiverID    int64    `json:"receiver_id"`
 	Name          string   `json:"name"`
 	Age           int      `json:"age"`
 	Location      string   `json:"location"`
@@ -205,10 +375,22 @@ type MatchRequestResponse struct {
 
 // GetIncomingMatchRequests retrieves all pending match requests for a user
 func (p *Postgres) GetIncomingMatchRequests(ctx context.Context, receiverID int64) ([]MatchRequestResponse, error) {
+	return p.queryMatchRequests(ctx, "mr.sender_id", "mr.receiver_id", receiverID)
+}
+
+// GetOutgoingMatchRequests retrieves all pending match requests a user has sent
+func (p *Postgres) GetOutgoingMatchRequests(ctx context.Context, senderID int64) ([]MatchRequestResponse, error) {
+	return p.queryMatchRequests(ctx, "mr.receiver_id", "mr.sender_id", senderID)
+}
+
+// queryMatchRequests lists pending requests where ownerCol equals userID,
+// joined with the profile of the user in otherCol
+func (p *Postgres) queryMatchRequests(ctx context.Context, otherCol, ownerCol string, userID int64) ([]MatchRequestResponse, error) {
 	query := `
 		SELECT 
 			mr.id,
 			mr.sender_id,
+			mr.receiver_id,
 			u.name,
 			COALESCE(u.age, 0) AS age,
 			COALESCE(u.city, '') AS city,
@@ -220,14 +402,14 @@ func (p *Postgres) GetIncomingMatchRequests(ctx context.Context, receiverID int6
 			COALESCE(s.total_score, 0) AS compatibility,
 			mr.created_at
 		FROM match_requests mr
-		INNER JOIN users u ON mr.sender_id = u.user_id
+		INNER JOIN users u ON ` + otherCol + ` = u.user_id
 		LEFT JOIN scores s ON u.user_id = s.user_id
-		WHERE mr.receiver_id = $1 
+		WHERE ` + ownerCol + ` = $1 
 		  AND mr.status = 'pending'
 		ORDER BY mr.created_at DESC
 	`
 
-	rows, err := p.Pool.Query(ctx, query, receiverID)
+	rows, err := p.Pool.Query(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -241,6 +423,7 @@ func (p *Postgres) GetIncomingMatchRequests(ctx context.Context, receiverID int6
 		err := rows.Scan(
 			&req.ID,
 			&req.SenderID,
+			&req.ReceiverID,
 			&req.Name,
 			&req.Age,
 			&req.Location,
@@ -277,6 +460,43 @@ func (p *Postgres) GetIncomingMatchRequests(ctx context.Context, receiverID int6
 	return requests, nil
 }
 
+// WithdrawMatchRequest withdraws a pending request the sender made and
+// drops the "liked" exclusion so the receiver can be recommended again.
+// It returns the receiver's ID.
+func (p *Postgres) WithdrawMatchRequest(ctx context.Context, senderID, requestID int64) (int64, error) {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var receiverID int64
+	err = tx.QueryRow(ctx, `
+		SELECT receiver_id FROM match_requests
+		WHERE id = $1 AND sender_id = $2
+	`, requestID, senderID).Scan(&receiverID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrRequestNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := transitionRequest(ctx, tx, senderID, receiverID, StatusWithdrawn); err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM user_exclusions
+		WHERE user_id = $1 AND target_id = $2 AND reason = 'liked'
+	`, senderID, receiverID)
+	if err != nil {
+		return 0, err
+	}
+
+	return receiverID, tx.Commit(ctx)
+}
+
 // MatchResponse represents a recent match with user details
 type MatchResponse struct {
 	MatchID       int64  `json:"match_id"`
@@ -313,7 +533,18 @@ func (p *Postgres) GetRecentMatches(ctx context.Context, userID int64) ([]MatchR
 			COALESCE(s.total_score, 0) AS compatibility,
 			m.matched_at,
 			COALESCE(msg.body, '') AS last_message,
-			msg.sent_at AS last_message_at
+			msg.sent_at AS last_message_at,
+			(
+				SELECT COUNT(*)
+				FROM messages
+				WHERE match_id = m.id
+				  AND deleted_at IS NULL
+				  AND sender_id <> $1
+				  AND id > COALESCE(
+					CASE WHEN m.user1_id = $1 THEN m.user1_last_read_message_id ELSE m.user2_last_read_message_id END,
+					0
+				  )
+			) AS unread_count
 		FROM matches m
 		INNER JOIN users u ON (
 			CASE 
@@ -325,11 +556,12 @@ func (p *Postgres) GetRecentMatches(ctx context.Context, userID int64) ([]MatchR
 		LEFT JOIN LATERAL (
 			SELECT body, sent_at
 			FROM messages
-			WHERE match_id = m.id
+			WHERE match_id = m.id AND deleted_at IS NULL
 			ORDER BY sent_at DESC
 			LIMIT 1
 		) msg ON true
-		WHERE m.user1_id = $1 OR m.user2_id = $1
+		WHERE (m.user1_id = $1 OR m.user2_id = $1)
+		  AND m.unmatched_at IS NULL
 		ORDER BY COALESCE(msg.sent_at, m.matched_at) DESC
 	`
 
@@ -355,6 +587,7 @@ func (p *Postgres) GetRecentMatches(ctx context.Context, userID int64) ([]MatchR
 			&matchedAt,
 			&match.LastMessage,
 			&lastMessageAt,
+			&match.UnreadCount,
 		)
 		if err != nil {
 			return nil, err
@@ -374,9 +607,6 @@ func (p *Postgres) GetRecentMatches(ctx context.Context, userID int64) ([]MatchR
 			match.LastMessageAt = "recently"
 		}
 
-		// Unread count would require tracking read status in DB, defaulting to 0
-		match.UnreadCount = 0
-
 		matches = append(matches, match)
 	}
 
@@ -0,0 +1,117 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Match request statuses. A request starts pending and is answered
+// (accepted or rejected), withdrawn by its sender, or expires. Answers are
+// final; a withdrawn or expired request can be reopened by liking again.
+const (
+	StatusPending   = "pending"
+	StatusAccepted  = "accepted"
+	StatusRejected  = "rejected"
+	StatusWithdrawn = "withdrawn"
+	StatusExpired   = "expired"
+)
+
+var (
+	// ErrRequestNotFound means there is no request between the two users
+	ErrRequestNotFound = errors.New("match request not found")
+	// ErrInvalidTransition means the request can't move to the new status
+	ErrInvalidTransition = errors.New("invalid match request status transition")
+)
+
+// requestTransitions lists the statuses each status may move to
+var requestTransitions = map[string][]string{
+	StatusPending:   {StatusAccepted, StatusRejected, StatusWithdrawn, StatusExpired},
+	StatusWithdrawn: {StatusPending},
+	StatusExpired:   {StatusPending},
+}
+
+// TransitionError reports a rejected status change.
+// It matches ErrInvalidTransition with errors.Is.
+type TransitionError struct {
+	From, To string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("match request cannot go from %s to %s", e.From, e.To)
+}
+
+// Is lets errors.Is(err, ErrInvalidTransition) match any TransitionError
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// canTransition reports whether a request may move from one status to another
+func canTransition(from, to string) bool {
+	for _, next := range requestTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionRequest locks the request from sender to receiver and moves
+// it to status to, returning the request ID. It must run inside tx so the
+// check and the update can't race with another transition.
+func transitionRequest(ctx context.Context, tx pgx.Tx, senderID, receiverID int64, to string) (int64, error) {
+	id, from, err := lockRequest(ctx, tx, senderID, receiverID)
+	if err != nil {
+		return 0, err
+	}
+	return id, setRequestStatus(ctx, tx, id, from, to)
+}
+
+// lockRequest locks the request from sender to receiver and returns its
+// ID and status, or ErrRequestNotFound
+func lockRequest(ctx context.Context, tx pgx.Tx, senderID, receiverID int64) (int64, string, error) {
+	var id int64
+	var status string
+	err := tx.QueryRow(ctx, `
+		SELECT id, status FROM match_requests
+		WHERE sender_id = $1 AND receiver_id = $2
+		FOR UPDATE
+	`, senderID, receiverID).Scan(&id, &status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, "", ErrRequestNotFound
+	}
+	return id, status, err
+}
+
+// setRequestStatus moves a request locked by lockRequest from one status
+// to another. Reopening a request restarts its expiry clock.
+func setRequestStatus(ctx context.Context, tx pgx.Tx, id int64, from, to string) error {
+	if !canTransition(from, to) {
+		return &TransitionError{From: from, To: to}
+	}
+
+	_, err := tx.Exec(ctx, `
+		UPDATE match_requests
+		SET status = $2, created_at = CASE WHEN $2 = 'pending' THEN NOW() ELSE created_at END
+		WHERE id = $1
+	`, id, to)
+	return err
+}
+
+// ExpireMatchRequests moves pending requests older than ttl to expired
+// and returns how many were expired. It's a bulk pending -> expired
+// transition, so it doesn't need the per-row lock in transitionRequest.
+func (p *Postgres) ExpireMatchRequests(ctx context.Context, ttl time.Duration) (int64, error) {
+	tag, err := p.Pool.Exec(ctx, `
+		UPDATE match_requests
+		SET status = 'expired'
+		WHERE status = 'pending' AND created_at < NOW() - $1::interval
+	`, ttl)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,85 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Report categories accepted by CreateReport
+var ReportCategories = []string{"spam", "harassment", "fake_profile", "inappropriate_content", "underage", "other"}
+
+// BlockUser hides two users from each other for good. The blocker's
+// exclusion is marked "blocked", pending requests between them are
+// closed and any active match is ended.
+func (p *Postgres) BlockUser(ctx context.Context, blockerID, blockedID int64) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO user_exclusions (user_id, target_id, reason)
+		VALUES ($1, $2, 'blocked')
+		ON CONFLICT (user_id, target_id)
+		DO UPDATE SET reason = EXCLUDED.reason, created_at = NOW()
+	`, blockerID, blockedID)
+	if err != nil {
+		return err
+	}
+
+	// Our own like is withdrawn, theirs is rejected
+	if _, err := transitionRequest(ctx, tx, blockerID, blockedID, StatusWithdrawn); err != nil &&
+		!errors.Is(err, ErrRequestNotFound) && !errors.Is(err, ErrInvalidTransition) {
+		return err
+	}
+	if _, err := transitionRequest(ctx, tx, blockedID, blockerID, StatusRejected); err != nil &&
+		!errors.Is(err, ErrRequestNotFound) && !errors.Is(err, ErrInvalidTransition) {
+		return err
+	}
+
+	var matchID int64
+	err = tx.QueryRow(ctx, `
+		SELECT id FROM matches
+		WHERE ((user1_id = $1 AND user2_id = $2) OR (user1_id = $2 AND user2_id = $1))
+		  AND unmatched_at IS NULL
+		FOR UPDATE
+	`, blockerID, blockedID).Scan(&matchID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if err == nil {
+		if err := endMatch(ctx, tx, matchID, blockerID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// IsBlocked reports whether either user has blocked the other
+func (p *Postgres) IsBlocked(ctx context.Context, a, b int64) (bool, error) {
+	var blocked bool
+	err := p.Pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM user_exclusions
+			WHERE reason = 'blocked'
+			  AND ((user_id = $1 AND target_id = $2) OR (user_id = $2 AND target_id = $1))
+		)
+	`, a, b).Scan(&blocked)
+	return blocked, err
+}
+
+// CreateReport files a report against a user for moderators to review
+// and returns its ID
+func (p *Postgres) CreateReport(ctx context.Context, reporterID, reportedID int64, category, details string) (int64, error) {
+	var id int64
+	err := p.Pool.QueryRow(ctx, `
+		INSERT INTO reports (reporter_id, reported_id, category, details)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+		RETURNING id
+	`, reporterID, reportedID, category, details).Scan(&id)
+	return id, err
+}
@@ -0,0 +1,54 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rishyym0927/match_backend/internal/core"
+)
+
+// GetUserPreferences returns a user's saved match preferences.
+// Users who never saved any get the zero value, meaning "any".
+func (p *Postgres) GetUserPreferences(ctx context.Context, userID int64) (core.Preferences, error) {
+	var prefs core.Preferences
+
+	query := `
+		SELECT 
+			COALESCE(target_gender, ''),
+			COALESCE(age_min, 0),
+			COALESCE(age_max, 0),
+			COALESCE(max_distance_km, 0),
+			COALESCE(min_score, 0)
+		FROM user_preferences
+		WHERE user_id = $1
+	`
+
+	err := p.Pool.QueryRow(ctx, query, userID).Scan(
+		&prefs.TargetGender, &prefs.AgeMin, &prefs.AgeMax, &prefs.MaxDistanceKm, &prefs.MinScore,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return core.Preferences{}, nil
+	}
+
+	return prefs, err
+}
+
+// UpsertUserPreferences saves a user's match preferences.
+// Zero values are stored as NULL so they don't constrain matching.
+func (p *Postgres) UpsertUserPreferences(ctx context.Context, userID int64, prefs core.Preferences) error {
+	query := `
+		INSERT INTO user_preferences (user_id, target_gender, age_min, age_max, max_distance_km, min_score, updated_at)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, 0), NULLIF($4, 0), NULLIF($5, 0::double precision), NULLIF($6, 0), NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET target_gender = EXCLUDED.target_gender,
+			age_min = EXCLUDED.age_min,
+			age_max = EXCLUDED.age_max,
+			max_distance_km = EXCLUDED.max_distance_km,
+			min_score = EXCLUDED.min_score,
+			updated_at = NOW()
+	`
+	_, err := p.Pool.Exec(ctx, query, userID,
+		prefs.TargetGender, prefs.AgeMin, prefs.AgeMax, prefs.MaxDistanceKm, prefs.MinScore)
+	return err
+}
@@ -0,0 +1,131 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MaxNotifyPayload is Postgres' NOTIFY payload limit (8000 bytes) less
+// some headroom
+const MaxNotifyPayload = 7900
+
+// ErrPayloadTooLarge means a message is too big to publish
+var ErrPayloadTooLarge = errors.New("pubsub payload too large")
+
+// PubSub fans messages out to every server instance listening on a channel
+type PubSub interface {
+	// Publish sends payload to all listeners of channel, including ones
+	// in this process
+	Publish(ctx context.Context, channel string, payload []byte) error
+	// Listen calls fn for every payload published on channel until ctx
+	// is cancelled. fn runs on the listener goroutine and must not block.
+	Listen(ctx context.Context, channel string, fn func(payload []byte)) error
+}
+
+// MemoryPubSub is an in-process PubSub for single-node runs
+type MemoryPubSub struct {
+	mu        sync.RWMutex
+	listeners map[string]map[*func([]byte)]struct{}
+}
+
+// NewMemoryPubSub creates an in-process PubSub
+func NewMemoryPubSub() *MemoryPubSub {
+	return &MemoryPubSub{listeners: make(map[string]map[*func([]byte)]struct{})}
+}
+
+// Publish calls every listener on channel synchronously
+func (m *MemoryPubSub) Publish(_ context.Context, channel string, payload []byte) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for fn := range m.listeners[channel] {
+		(*fn)(payload)
+	}
+	return nil
+}
+
+// Listen registers fn on channel and blocks until ctx is cancelled
+func (m *MemoryPubSub) Listen(ctx context.Context, channel string, fn func([]byte)) error {
+	key := &fn
+
+	m.mu.Lock()
+	if m.listeners[channel] == nil {
+		m.listeners[channel] = make(map[*func([]byte)]struct{})
+	}
+	m.listeners[channel][key] = struct{}{}
+	m.mu.Unlock()
+
+	<-ctx.Done()
+
+	m.mu.Lock()
+	delete(m.listeners[channel], key)
+	m.mu.Unlock()
+	return nil
+}
+
+// PgPubSub is a PubSub over Postgres LISTEN/NOTIFY, so every replica
+// sharing the database sees every message
+type PgPubSub struct {
+	pg *Postgres
+}
+
+// NewPgPubSub creates a PubSub on the given pool
+func NewPgPubSub(pg *Postgres) *PgPubSub {
+	return &PgPubSub{pg: pg}
+}
+
+// Publish sends payload with pg_notify. Payloads must fit NOTIFY's limit.
+func (ps *PgPubSub) Publish(ctx context.Context, channel string, payload []byte) error {
+	if len(payload) > MaxNotifyPayload {
+		return ErrPayloadTooLarge
+	}
+	_, err := ps.pg.Pool.Exec(ctx, `SELECT pg_notify($1, $2)`, channel, string(payload))
+	return err
+}
+
+// Listen holds a pooled connection on LISTEN and calls fn for each
+// notification. Lost connections are re-established after a short wait;
+// notifications sent while reconnecting are missed.
+func (ps *PgPubSub) Listen(ctx context.Context, channel string, fn func([]byte)) error {
+	for {
+		err := ps.listenOnce(ctx, channel, fn)
+		if ctx.Err() != nil {
+			return nil
+		}
+		log.Printf("pubsub: listener on %q lost, reconnecting: %v", channel, err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (ps *PgPubSub) listenOnce(ctx context.Context, channel string, fn func([]byte)) error {
+	pooled, err := ps.pg.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// Take the connection out of the pool; a LISTENing session must not be
+	// handed to other queries
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return err
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		fn([]byte(n.Payload))
+	}
+}
@@ -0,0 +1,112 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// listen starts m.Listen on channel in the background and waits until the
+// listener is registered. Cancelling the returned context stops it; done
+// is closed once Listen has returned.
+func listen(t *testing.T, m *MemoryPubSub, channel string, fn func([]byte)) (cancel context.CancelFunc, done <-chan struct{}) {
+	t.Helper()
+
+	before := listeners(m, channel)
+	ctx, cancel := context.WithCancel(context.Background())
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		if err := m.Listen(ctx, channel, fn); err != nil {
+			t.Errorf("Listen returned %v", err)
+		}
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for listeners(m, channel) == before {
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatal("listener never registered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return cancel, finished
+}
+
+func listeners(m *MemoryPubSub, channel string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.listeners[channel])
+}
+
+func TestMemoryPubSubDelivers(t *testing.T) {
+	m := NewMemoryPubSub()
+
+	var got, other [][]byte
+	cancel, _ := listen(t, m, "a", func(p []byte) { got = append(got, p) })
+	defer cancel()
+	cancelOther, _ := listen(t, m, "b", func(p []byte) { other = append(other, p) })
+	defer cancelOther()
+
+	if err := m.Publish(context.Background(), "a", []byte("hello")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	// Publish is synchronous, so delivery has already happened
+	if len(got) != 1 || !bytes.Equal(got[0], []byte("hello")) {
+		t.Fatalf("listener on a got %q, want [hello]", got)
+	}
+	if len(other) != 0 {
+		t.Fatalf("listener on b got %q, want nothing", other)
+	}
+}
+
+func TestMemoryPubSubFansOut(t *testing.T) {
+	m := NewMemoryPubSub()
+
+	var first, second int
+	cancel1, _ := listen(t, m, "a", func([]byte) { first++ })
+	defer cancel1()
+	cancel2, _ := listen(t, m, "a", func([]byte) { second++ })
+	defer cancel2()
+
+	m.Publish(context.Background(), "a", []byte("x"))
+
+	if first != 1 || second != 1 {
+		t.Fatalf("deliveries = %d, %d; want 1 each", first, second)
+	}
+}
+
+func TestMemoryPubSubUnsubscribesOnCancel(t *testing.T) {
+	m := NewMemoryPubSub()
+
+	var got int
+	cancel, done := listen(t, m, "a", func([]byte) { got++ })
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Listen did not return after cancel")
+	}
+	if n := listeners(m, "a"); n != 0 {
+		t.Fatalf("%d listeners left after cancel, want 0", n)
+	}
+
+	m.Publish(context.Background(), "a", []byte("late"))
+	if got != 0 {
+		t.Fatalf("cancelled listener got %d payloads, want 0", got)
+	}
+}
+
+func TestPgPubSubRejectsOversizedPayload(t *testing.T) {
+	// The size check runs before the pool is touched, so no database is needed
+	ps := &PgPubSub{}
+
+	err := ps.Publish(context.Background(), "a", make([]byte, MaxNotifyPayload+1))
+	if !errors.Is(err, ErrPayloadTooLarge) {
+		t.Fatalf("Publish = %v, want ErrPayloadTooLarge", err)
+	}
+}
@@ -25,6 +25,7 @@ type RequestStatistics struct {
 	Accepted       int     `json:"accepted"`
 	Rejected       int     `json:"rejected"`
 	Pending        int     `json:"pending"`
+	Expired        int     `json:"expired"`
 	ThisWeek       int     `json:"this_week"`
 	AcceptanceRate float64 `json:"acceptance_rate"`
 }
@@ -135,6 +136,7 @@ func (p *Postgres) GetRequestStatistics(ctx context.Context, userID int64) (Requ
 			COALESCE(SUM(CASE WHEN status = 'accepted' THEN 1 ELSE 0 END), 0) AS accepted,
 			COALESCE(SUM(CASE WHEN status = 'rejected' THEN 1 ELSE 0 END), 0) AS rejected,
 			COALESCE(SUM(CASE WHEN status = 'pending' THEN 1 ELSE 0 END), 0) AS pending,
+			COALESCE(SUM(CASE WHEN status = 'expired' THEN 1 ELSE 0 END), 0) AS expired,
 			COALESCE(
 				SUM(CASE WHEN created_at >= $2 THEN 1 ELSE 0 END), 
 				0
@@ -151,6 +153,7 @@ func (p *Postgres) GetRequestStatistics(ctx context.Context, userID int64) (Requ
 		&stats.Accepted,
 		&stats.Rejected,
 		&stats.Pending,
+		&stats.Expired,
 		&stats.ThisWeek,
 	)
 
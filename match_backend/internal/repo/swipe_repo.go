@@ -0,0 +1,172 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrNothingToUndo means the user has no swipe inside the undo window
+	ErrNothingToUndo = errors.New("no swipe to undo")
+	// ErrUndoNotAllowed means the last swipe already had consequences,
+	// e.g. a like that was accepted and became a match
+	ErrUndoNotAllowed = errors.New("swipe can no longer be undone")
+	// ErrSwipeQuotaExceeded means the user has used up today's swipes
+	ErrSwipeQuotaExceeded = errors.New("daily swipe limit reached")
+)
+
+// SwipeQuota caps likes and rejections per user since a point in time.
+// A zero Limit means unlimited.
+type SwipeQuota struct {
+	Limit int
+	Since time.Time
+}
+
+// UnlimitedSwipes is the remaining count reported when there is no quota
+const UnlimitedSwipes = -1
+
+// SwipeRecord is one entry in a user's swipe history
+type SwipeRecord struct {
+	ID        int64     `json:"id"`
+	TargetID  int64     `json:"target_id"`
+	Action    string    `json:"action"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CountSwipesSince counts likes and rejections a user made since the
+// given time. Undone swipes still count, so undo can't refill the quota.
+func (p *Postgres) CountSwipesSince(ctx context.Context, userID int64, since time.Time) (int, error) {
+	var count int
+	err := p.Pool.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM swipe_history
+		WHERE user_id = $1
+		  AND action IN ('liked', 'rejected')
+		  AND created_at >= $2
+	`, userID, since).Scan(&count)
+	return count, err
+}
+
+// RejectUser records a rejection (swipe left) and excludes the target
+// from future recommendations. It returns the swipes left under quota.
+func (p *Postgres) RejectUser(ctx context.Context, userID, targetID int64, quota SwipeQuota) (int, error) {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	remaining, err := recordSwipe(ctx, tx, userID, targetID, "rejected", quota)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, addExclusionSQL, userID, targetID, "rejected"); err != nil {
+		return 0, err
+	}
+
+	return remaining, tx.Commit(ctx)
+}
+
+// recordSwipe appends a like or rejection to the user's swipe history
+// inside tx, failing with ErrSwipeQuotaExceeded once the quota is used
+// up. The user's row is locked so concurrent swipes are counted in turn.
+// It returns how many swipes are left afterwards, or UnlimitedSwipes.
+func recordSwipe(ctx context.Context, tx pgx.Tx, userID, targetID int64, action string, quota SwipeQuota) (int, error) {
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE user_id = $1 FOR UPDATE`, userID); err != nil {
+		return 0, err
+	}
+
+	remaining := UnlimitedSwipes
+	if quota.Limit > 0 {
+		var used int
+		err := tx.QueryRow(ctx, `
+			SELECT COUNT(*)
+			FROM swipe_history
+			WHERE user_id = $1
+			  AND action IN ('liked', 'rejected')
+			  AND created_at >= $2
+		`, userID, quota.Since).Scan(&used)
+		if err != nil {
+			return 0, err
+		}
+		if used >= quota.Limit {
+			return 0, ErrSwipeQuotaExceeded
+		}
+		remaining = quota.Limit - used - 1
+	}
+
+	_, err := tx.Exec(ctx,
+		`INSERT INTO swipe_history (user_id, target_id, action) VALUES ($1, $2, $3)`,
+		userID, targetID, action)
+	return remaining, err
+}
+
+// UndoLastSwipe reverses the user's most recent like or rejection made
+// within window: the exclusion is removed, a like's pending request is
+// withdrawn, and the undo itself is recorded in the history
+func (p *Postgres) UndoLastSwipe(ctx context.Context, userID int64, window time.Duration) (SwipeRecord, error) {
+	var rec SwipeRecord
+
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return rec, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		SELECT id, target_id, action, created_at
+		FROM swipe_history
+		WHERE user_id = $1
+		  AND action IN ('liked', 'rejected')
+		  AND undone_at IS NULL
+		  AND created_at >= NOW() - $2::interval
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+		FOR UPDATE
+	`, userID, window).Scan(&rec.ID, &rec.TargetID, &rec.Action, &rec.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return rec, ErrNothingToUndo
+	}
+	if err != nil {
+		return rec, err
+	}
+
+	if rec.Action == "liked" {
+		// Withdraw the like; once it has been answered it can't be taken
+		// back. A request already withdrawn by hand needs nothing more.
+		_, err = transitionRequest(ctx, tx, userID, rec.TargetID, StatusWithdrawn)
+		var te *TransitionError
+		switch {
+		case errors.As(err, &te) && te.From == StatusWithdrawn:
+		case errors.Is(err, ErrInvalidTransition):
+			return rec, ErrUndoNotAllowed
+		case err != nil && !errors.Is(err, ErrRequestNotFound):
+			return rec, err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM user_exclusions
+		WHERE user_id = $1 AND target_id = $2 AND reason = $3
+	`, userID, rec.TargetID, rec.Action)
+	if err != nil {
+		return rec, err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE swipe_history SET undone_at = NOW() WHERE id = $1`, rec.ID)
+	if err != nil {
+		return rec, err
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO swipe_history (user_id, target_id, action) VALUES ($1, $2, 'undo')`,
+		userID, rec.TargetID)
+	if err != nil {
+		return rec, err
+	}
+
+	return rec, tx.Commit(ctx)
+}
@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/rishyym0927/match_backend/internal/core"
+)
+
+// FetchLabeledPairs returns every decided match request with both users'
+// traits, for training compatibility models offline
+func (p *Postgres) FetchLabeledPairs(ctx context.Context) ([]core.LabeledPair, error) {
+	query := `
+		SELECT 
+			su.user_id, su.gender, COALESCE(su.age, 0), COALESCE(su.city, ''),
+			COALESCE(su.lat, 0.0), COALESCE(su.lon, 0.0),
+			COALESCE(ss.total_score, 0), COALESCE(ss.personality, 0), COALESCE(ss.communication, 0),
+			COALESCE(ss.emotional, 0), COALESCE(ss.confidence, 0),
+			ru.user_id, ru.gender, COALESCE(ru.age, 0), COALESCE(ru.city, ''),
+			COALESCE(ru.lat, 0.0), COALESCE(ru.lon, 0.0),
+			COALESCE(rs.total_score, 0), COALESCE(rs.personality, 0), COALESCE(rs.communication, 0),
+			COALESCE(rs.emotional, 0), COALESCE(rs.confidence, 0),
+			mr.status = 'accepted' AS accepted
+		FROM match_requests mr
+		INNER JOIN users su ON mr.sender_id = su.user_id
+		INNER JOIN users ru ON mr.receiver_id = ru.user_id
+		LEFT JOIN scores ss ON su.user_id = ss.user_id
+		LEFT JOIN scores rs ON ru.user_id = rs.user_id
+		WHERE mr.status IN ('accepted', 'rejected')
+		ORDER BY mr.id
+	`
+
+	rows, err := p.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pairs []core.LabeledPair
+	for rows.Next() {
+		var lp core.LabeledPair
+		s, r := &lp.Sender, &lp.Receiver
+		if err := rows.Scan(
+			&s.ID, &s.Gender, &s.Age, &s.City, &s.Lat, &s.Lon,
+			&s.TotalScore, &s.Personality, &s.Communication, &s.Emotional, &s.Confidence,
+			&r.ID, &r.Gender, &r.Age, &r.City, &r.Lat, &r.Lon,
+			&r.TotalScore, &r.Personality, &r.Communication, &r.Emotional, &r.Confidence,
+			&lp.Accepted,
+		); err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, lp)
+	}
+
+	return pairs, rows.Err()
+}
@@ -0,0 +1,103 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrMatchNotFound means there is no match with the given ID
+	ErrMatchNotFound = errors.New("match not found")
+	// ErrNotMatchParticipant means the user isn't one of the two matched users
+	ErrNotMatchParticipant = errors.New("not a participant in this match")
+	// ErrMatchEnded means one of the users has unmatched
+	ErrMatchEnded = errors.New("match has ended")
+	// ErrUnmatched means the two users matched before and one unmatched,
+	// so they can't be matched again
+	ErrUnmatched = errors.New("users have unmatched")
+)
+
+// Unmatch ends a match on behalf of one participant. The match and its
+// messages are soft-deleted and both users exclude each other so they
+// never resurface in recommendations. It returns the other user's ID.
+func (p *Postgres) Unmatch(ctx context.Context, matchID, userID int64) (int64, error) {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var user1ID, user2ID int64
+	var ended bool
+	err = tx.QueryRow(ctx, `
+		SELECT user1_id, user2_id, unmatched_at IS NOT NULL
+		FROM matches
+		WHERE id = $1
+		FOR UPDATE
+	`, matchID).Scan(&user1ID, &user2ID, &ended)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrMatchNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var otherID int64
+	switch userID {
+	case user1ID:
+		otherID = user2ID
+	case user2ID:
+		otherID = user1ID
+	default:
+		return 0, ErrNotMatchParticipant
+	}
+	if ended {
+		return otherID, ErrMatchEnded
+	}
+
+	if err := endMatch(ctx, tx, matchID, userID); err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO user_exclusions (user_id, target_id, reason)
+		VALUES ($1, $2, 'unmatched'), ($2, $1, 'unmatched')
+		ON CONFLICT (user_id, target_id)
+		DO UPDATE SET reason = EXCLUDED.reason, created_at = NOW()
+	`, userID, otherID)
+	if err != nil {
+		return 0, err
+	}
+
+	return otherID, tx.Commit(ctx)
+}
+
+// endMatch soft-deletes a match and its messages
+func endMatch(ctx context.Context, tx pgx.Tx, matchID, byUserID int64) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE matches SET unmatched_at = NOW(), unmatched_by = $2 WHERE id = $1
+	`, matchID, byUserID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE messages SET deleted_at = NOW() WHERE match_id = $1 AND deleted_at IS NULL
+	`, matchID)
+	return err
+}
+
+// hasUnmatched reports whether either user unmatched the other
+func hasUnmatched(ctx context.Context, tx pgx.Tx, a, b int64) (bool, error) {
+	var unmatched bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM user_exclusions
+			WHERE reason = 'unmatched'
+			  AND ((user_id = $1 AND target_id = $2) OR (user_id = $2 AND target_id = $1))
+		)
+	`, a, b).Scan(&unmatched)
+	return unmatched, err
+}
@@ -81,6 +81,50 @@ func (p *Postgres) GetUserImageURLs(ctx context.Context, userID int64) ([]string
 	return urls, rows.Err()
 }
 
+// GetUserImageURLsBatch returns image URLs for many users in one query.
+// Every requested ID is present in the result; users without images
+// get the default image, matching GetUserImageURLs.
+func (p *Postgres) GetUserImageURLsBatch(ctx context.Context, userIDs []int64) (map[int64][]string, error) {
+	urls := make(map[int64][]string, len(userIDs))
+	if len(userIDs) == 0 {
+		return urls, nil
+	}
+
+	rows, err := p.Pool.Query(ctx,
+		`SELECT user_id, COALESCE(public_url, '') 
+		 FROM user_images 
+		 WHERE user_id = ANY($1) 
+		 ORDER BY user_id, is_primary DESC, uploaded_at DESC;`,
+		userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID int64
+		var url string
+		if err := rows.Scan(&userID, &url); err != nil {
+			return nil, err
+		}
+		if url != "" {
+			urls[userID] = append(urls[userID], url)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// If no images, return default
+	for _, id := range userIDs {
+		if len(urls[id]) == 0 {
+			urls[id] = []string{"/default.jpg"}
+		}
+	}
+
+	return urls, nil
+}
+
 func (p *Postgres) DeleteUserImage(ctx context.Context, imageID, userID int64) error {
 	_, err := p.Pool.Exec(ctx, `DELETE FROM user_images WHERE id=$1 AND user_id=$2;`, imageID, userID)
 	return err
@@ -1,3 +1,8 @@
+DROP TABLE IF EXISTS reports CASCADE;
+DROP TABLE IF EXISTS swipe_history CASCADE;
+DROP TABLE IF EXISTS daily_pick_runs CASCADE;
+DROP TABLE IF EXISTS daily_picks CASCADE;
+DROP TABLE IF EXISTS user_preferences CASCADE;
 DROP TABLE IF EXISTS messages CASCADE;
 DROP TABLE IF EXISTS matches CASCADE;
 DROP TABLE IF EXISTS match_requests CASCADE;
@@ -52,11 +57,13 @@ CREATE TABLE IF NOT EXISTS match_requests (
     id SERIAL PRIMARY KEY,
     sender_id BIGINT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
     receiver_id BIGINT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
-    status VARCHAR(20) DEFAULT 'pending' CHECK (status IN ('pending','accepted','rejected')),
+    status VARCHAR(20) DEFAULT 'pending' CHECK (status IN ('pending','accepted','rejected','withdrawn','expired')),
     created_at TIMESTAMP DEFAULT NOW(),
     UNIQUE(sender_id, receiver_id)
 );
 
+CREATE INDEX IF NOT EXISTS idx_match_requests_pending ON match_requests(created_at) WHERE status = 'pending';
+
 -- ========================================
 -- 5. Matches
 -- ========================================
@@ -65,6 +72,10 @@ CREATE TABLE IF NOT EXISTS matches (
     user1_id BIGINT REFERENCES users(user_id) ON DELETE CASCADE,
     user2_id BIGINT REFERENCES users(user_id) ON DELETE CASCADE,
     matched_at TIMESTAMP DEFAULT NOW(),
+    unmatched_at TIMESTAMP,                -- set when either user unmatches
+    unmatched_by BIGINT REFERENCES users(user_id) ON DELETE SET NULL,
+    user1_last_read_message_id BIGINT,     -- read receipts, one per participant
+    user2_last_read_message_id BIGINT,
     UNIQUE(user1_id, user2_id)
 );
 
@@ -76,7 +87,8 @@ CREATE TABLE IF NOT EXISTS messages (
     match_id BIGINT NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
     sender_id BIGINT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
     body TEXT NOT NULL,
-    sent_at TIMESTAMP DEFAULT NOW()
+    sent_at TIMESTAMP DEFAULT NOW(),
+    deleted_at TIMESTAMP                   -- soft delete, e.g. after an unmatch
 );
 
 CREATE INDEX IF NOT EXISTS idx_messages_match ON messages(match_id, sent_at);
@@ -96,9 +108,82 @@ CREATE TABLE IF NOT EXISTS user_images (
 CREATE INDEX IF NOT EXISTS idx_user_images_user ON user_images(user_id, uploaded_at DESC);
 CREATE INDEX IF NOT EXISTS idx_user_images_primary ON user_images(user_id, is_primary);
 
+-- ========================================
+-- 7. User Preferences
+-- ========================================
+-- What each user is looking for. NULL columns mean "any". Used on both
+-- sides of a recommendation: candidates must also accept the viewer.
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id BIGINT PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+    target_gender CHAR(1) CHECK (target_gender IN ('M', 'F')),
+    age_min SMALLINT CHECK (age_min BETWEEN 18 AND 100),
+    age_max SMALLINT CHECK (age_max BETWEEN 18 AND 100),
+    max_distance_km DOUBLE PRECISION CHECK (max_distance_km > 0),
+    min_score SMALLINT CHECK (min_score BETWEEN 0 AND 100),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    CHECK (age_min IS NULL OR age_max IS NULL OR age_min <= age_max)
+);
 
 -- ========================================
--- 7. Data Seeding
+-- 8. Daily Picks
+-- ========================================
+-- "Top picks today", precomputed by a background job in the server
+CREATE TABLE IF NOT EXISTS daily_picks (
+    user_id BIGINT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    pick_date DATE NOT NULL,
+    candidate_id BIGINT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    rank SMALLINT NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    match_score SMALLINT NOT NULL,
+    reasons TEXT[],
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (user_id, pick_date, candidate_id)
+);
+
+-- One row per generated day, so a day with no candidates isn't recomputed
+CREATE TABLE IF NOT EXISTS daily_pick_runs (
+    user_id BIGINT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    pick_date DATE NOT NULL,
+    generated_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (user_id, pick_date)
+);
+
+-- ========================================
+-- 9. Swipe History
+-- ========================================
+-- Audit log of likes, rejections and undos. user_exclusions holds the
+-- current state; this keeps every action so the last one can be rewound.
+CREATE TABLE IF NOT EXISTS swipe_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    target_id BIGINT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('liked','rejected','undo')),
+    created_at TIMESTAMP DEFAULT NOW(),
+    undone_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_swipe_history_user ON swipe_history(user_id, created_at DESC);
+
+-- ========================================
+-- 10. Reports
+-- ========================================
+-- User reports waiting for moderator review
+CREATE TABLE IF NOT EXISTS reports (
+    id BIGSERIAL PRIMARY KEY,
+    reporter_id BIGINT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    reported_id BIGINT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    category VARCHAR(30) NOT NULL CHECK (category IN ('spam','harassment','fake_profile','inappropriate_content','underage','other')),
+    details TEXT,
+    status VARCHAR(20) DEFAULT 'open' CHECK (status IN ('open','reviewing','resolved','dismissed')),
+    created_at TIMESTAMP DEFAULT NOW(),
+    reviewed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_status ON reports(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_reports_reported ON reports(reported_id);
+
+-- ========================================
+-- 11. Data Seeding
 -- ========================================
 
 -- Users
@@ -124,6 +209,10 @@ INSERT INTO user_exclusions (user_id, target_id, reason) VALUES
 (1,6,'blocked'),
 (2,4,'rejected');
 
+-- Preferences
+INSERT INTO user_preferences (user_id, target_gender, age_min, age_max) VALUES
+(4,'M',25,30);
+
 -- Match Requests
 INSERT INTO match_requests (sender_id, receiver_id, status) VALUES
 (1,4,'pending'),